@@ -0,0 +1,18 @@
+package controllers
+
+import "context"
+
+type contextKey int
+
+const idAndRoleContextKey contextKey = iota
+
+// WithIDAndRole returns a copy of ctx carrying the authenticated user
+func WithIDAndRole(ctx context.Context, IDAndRole *IDAndRole) context.Context {
+	return context.WithValue(ctx, idAndRoleContextKey, IDAndRole)
+}
+
+// IDAndRoleFromContext returns the authenticated user of the request, nil if the request is not authenticated
+func IDAndRoleFromContext(ctx context.Context) *IDAndRole {
+	IDAndRole, _ := ctx.Value(idAndRoleContextKey).(*IDAndRole)
+	return IDAndRole
+}
@@ -2,14 +2,16 @@ package controllers
 
 import (
 	"crypto/rand"
+	"database/sql"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/cache"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -17,7 +19,8 @@ import (
 func (apiConfig *ApiConfig) HandleSendOTP(w http.ResponseWriter, r *http.Request) {
 	// extracting email from request body
 	type email struct {
-		Email string `json:"email"`
+		Email   string `json:"email"`
+		Purpose string `json:"purpose"`
 	}
 
 	decoder := json.NewDecoder(r.Body)
@@ -34,10 +37,17 @@ func (apiConfig *ApiConfig) HandleSendOTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// validating the purpose the otp is requested for
+	purpose, err := cache.ParsePurpose(params.Purpose)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// sending otp
-	verificationToken, err := apiConfig.OtpCache.SendOTP(params.Email)
+	verificationToken, err := apiConfig.OtpCache.SendOTP(r.Context(), params.Email, purpose)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
@@ -72,8 +82,8 @@ func (apiConfig *ApiConfig) HandleRegisterUser(w http.ResponseWriter, r *http.Re
 	}
 
 	// validating the otp
-	if err = apiConfig.OtpCache.VerifyOTP(params.VerificationToken, params.OTP); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+	if err = apiConfig.OtpCache.VerifyOTP(r.Context(), params.VerificationToken, params.OTP, params.Email, cache.PurposeRegistration); err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
@@ -128,8 +138,8 @@ func (apiConfig *ApiConfig) HandleResendOTP(w http.ResponseWriter, r *http.Reque
 	}
 
 	// checking if resend is allowed or not
-	if isResendAllowed, err := apiConfig.OtpCache.IsResendAllowed(params.OldVerificationToken); !isResendAllowed {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+	if isResendAllowed, err := apiConfig.OtpCache.IsResendAllowed(r.Context(), params.OldVerificationToken); !isResendAllowed {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
@@ -139,13 +149,18 @@ func (apiConfig *ApiConfig) HandleResendOTP(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	// resending the otp
-	if err = apiConfig.OtpCache.ResendOTP(params.OldVerificationToken, params.Email); err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+	// resending the otp for the same purpose the old one was issued for
+	verificationToken, err := apiConfig.OtpCache.ResendOTP(r.Context(), params.OldVerificationToken, params.Email)
+	if err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusOK, nil)
+	utility.RespondWithJson(w, http.StatusOK, struct {
+		VerificationToken string `json:"verification_token"`
+	}{
+		VerificationToken: verificationToken,
+	})
 }
 
 func (apiConfig *ApiConfig) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -168,42 +183,66 @@ func (apiConfig *ApiConfig) HandleLogin(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// checking if the user exist or not
-	user, err := apiConfig.DB.GetUserByEmailID(r.Context(), params.Email)
+	// checking if logins for this account or from this address are locked
+	email := strings.ToLower(params.Email)
+	ip := clientIP(r)
+	lockedUntil, err := apiConfig.loginLockedUntil(r.Context(), email, ip)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusNotFound, err.Error())
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !lockedUntil.IsZero() {
+		respondWithLoginLocked(w, lockedUntil)
 		return
 	}
 
-	// comparing passwords
-	if err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(params.Password)); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+	// checking if the user exist and the password matches, both failures get
+	// the same response so that registered emails cannot be discovered
+	user, err := apiConfig.DB.GetUserByEmailID(r.Context(), email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	passwordHash := dummyPasswordHash
+	if err == nil {
+		passwordHash = []byte(user.Password)
+	}
+	if bcrypt.CompareHashAndPassword(passwordHash, []byte(params.Password)) != nil || err != nil {
+		if err = apiConfig.recordLoginFailure(r.Context(), email, ip); err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utility.RespondWithError(w, http.StatusUnauthorized, errInvalidCredentials.Error())
 		return
 	}
 
-	// creating access token
-	accessToken, err := MakeJWT(struct {
-		UserId string
-		Role   string
-	}{
-		UserId: user.ID.String(),
-		Role:   user.RoleName}, apiConfig.JwtSecret, 2*time.Hour)
-	if err != nil {
+	// forgetting the failed logins of the account after a successful one
+	if _, err = apiConfig.DB.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{
+		Scope:      loginScopeAccount,
+		Identifier: email,
+	}); err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// generating refresh token
-	refreshToken, err := generateRefreshToken()
+	// creating a new session for this device along with its refresh token
+	deviceName := params.DeviceName
+	if deviceName == "" {
+		deviceName = "unknown device"
+	}
+	sessionID, refreshToken, err := apiConfig.createSession(r.Context(), user.ID, deviceName, r.UserAgent())
 	if err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	if err = apiConfig.DB.CreateRefreshToken(r.Context(), database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().UTC().Add(time.Hour * 24 * 60),
-	}); err != nil {
+
+	// creating access token
+	accessToken, err := apiConfig.TokenManager.Issue(token.Claims{
+		UserID:    user.ID.String(),
+		Role:      user.RoleName,
+		SessionID: sessionID.String(),
+	}, token.AccessTokenExpiry)
+	if err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -212,32 +251,138 @@ func (apiConfig *ApiConfig) HandleLogin(w http.ResponseWriter, r *http.Request)
 		Username:      user.Username,
 		ProfilePicUrl: user.ProfilePicUrl,
 		AccessToken:   accessToken,
+		RefreshToken:  refreshToken,
 	})
 }
 
-func MakeJWT(tokenClaims struct {
-	UserId string
-	Role   string
-}, tokenSecret string, expiresIn time.Duration) (string, error) {
-	// creating the signing key to be used for signing the token
-	signingKey := []byte(tokenSecret)
+func (apiConfig *ApiConfig) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	type forgotPasswordRequest struct {
+		Email string `json:"email"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := forgotPasswordRequest{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// validating email
+	if err = apiConfig.DataValidator.Var(params.Email, "required,email"); err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// creating token claims
-	claims := &jwt.RegisteredClaims{
-		Issuer:    "http://localhost:8080",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
-		Subject:   `user_id:` + tokenClaims.UserId + `,role:` + tokenClaims.Role,
+	// checking if the user exist, the response and its timing are the same either
+	// way so that this endpoint cannot be used to find registered emails
+	_, err = apiConfig.DB.GetUserByEmailID(r.Context(), strings.ToLower(params.Email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	// signing the access token with the signing key
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS512, claims)
-	signedAccessToken, err := accessToken.SignedString(signingKey)
+	verificationToken, err := apiConfig.OtpCache.SendPasswordResetOTP(r.Context(), params.Email, err == nil)
 	if err != nil {
-		return "", err
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
+		return
 	}
 
-	return signedAccessToken, nil
+	utility.RespondWithJson(w, http.StatusOK, struct {
+		VerificationToken string `json:"verification_token"`
+	}{
+		VerificationToken: verificationToken,
+	})
+}
+
+func (apiConfig *ApiConfig) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	type resetPasswordRequest struct {
+		Email             string `json:"email"`
+		VerificationToken string `json:"verification_token"`
+		OTP               string `json:"otp"`
+		NewPassword       string `json:"new_password"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := resetPasswordRequest{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// validating email and the new password
+	if err = apiConfig.DataValidator.Var(params.Email, "required,email"); err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err = apiConfig.DataValidator.Var(params.NewPassword, "required,min=6,max=64,password"); err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// checking if the otp was sent to this email for a password reset
+	if err = apiConfig.OtpCache.VerifyOTP(r.Context(), params.VerificationToken, params.OTP, params.Email, cache.PurposePasswordReset); err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
+		return
+	}
+
+	// the user could have been removed after the otp was sent
+	user, err := apiConfig.DB.GetUserByEmailID(r.Context(), strings.ToLower(params.Email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utility.RespondWithError(w, http.StatusBadRequest, cache.ErrOTPNotFound.Error())
+			return
+		}
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// hashing the new password
+	newPassword, err := bcrypt.GenerateFromPassword([]byte(params.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// resetting the password
+	if err = apiConfig.DB.UpdatePassword(r.Context(), database.UpdatePasswordParams{
+		Password: string(newPassword),
+		ID:       user.ID,
+	}); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// revoking all the existing sessions as the old password could have been compromised
+	if err = apiConfig.revokeAllSessions(r.Context(), user.ID); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+// maps the errors returned by the otp cache to a response status code
+func otpErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, cache.ErrOTPExpired):
+		return http.StatusGone
+	case errors.Is(err, cache.ErrOTPLocked), errors.Is(err, cache.ErrOTPQuotaExceeded), errors.Is(err, cache.ErrOTPResendTooSoon):
+		return http.StatusTooManyRequests
+	case errors.Is(err, cache.ErrOTPIncorrect), errors.Is(err, cache.ErrOTPNotFound), errors.Is(err, cache.ErrOTPMismatch):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (apiConfig *ApiConfig) HandleJWKS(w http.ResponseWriter, r *http.Request) {
+	// the key set rarely changes so verifiers are allowed to cache it
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utility.RespondWithJson(w, http.StatusOK, apiConfig.TokenManager.JWKS())
 }
 
 func generateRefreshToken() (string, error) {
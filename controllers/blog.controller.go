@@ -4,17 +4,23 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
 // admin
-func (apiConfig *ApiConfig) HandleCreateBlog(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleCreateBlog(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		Title        string            `json:"title"`
 		Brief        string            `json:"brief,omitempty"`
@@ -27,11 +33,10 @@ func (apiConfig *ApiConfig) HandleCreateBlog(w http.ResponseWriter, r *http.Requ
 	}
 
 	type Response struct {
-		ID          uuid.UUID `json:"id"`
-		Blog        Request   `json:"blog"`
-		CreatedAt   time.Time `json:"createdAt"`
-		UpdatedAt   time.Time `json:"updatedAt"`
-		AccessToken string    `json:"accessToken"`
+		ID        uuid.UUID `json:"id"`
+		Blog      Request   `json:"blog"`
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
 	}
 
 	// decoding request body
@@ -115,6 +120,8 @@ func (apiConfig *ApiConfig) HandleCreateBlog(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusCreated, Response{
 		ID: newBlog.ID,
 		Blog: Request{
@@ -124,14 +131,13 @@ func (apiConfig *ApiConfig) HandleCreateBlog(w http.ResponseWriter, r *http.Requ
 			Tags:         newBlog.Tags,
 			Category:     params.Category,
 		},
-		CreatedAt:   newBlog.CreatedAt,
-		UpdatedAt:   newBlog.UpdatedAt,
-		AccessToken: newAccessToken,
+		CreatedAt: newBlog.CreatedAt,
+		UpdatedAt: newBlog.UpdatedAt,
 	})
 }
 
 // admin
-func (apiConfig *ApiConfig) HandleUpdateBlog(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateBlog(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		ID           uuid.UUID         `json:"id"`
 		Title        string            `json:"title"`
@@ -152,7 +158,6 @@ func (apiConfig *ApiConfig) HandleUpdateBlog(w http.ResponseWriter, r *http.Requ
 		Tags         []string          `json:"tags,omitempty"`
 		CreatedAt    time.Time         `json:"createdAt"`
 		UpdatedAt    time.Time         `json:"updatedAt"`
-		AccessToken  string            `json:"accessToken"`
 	}
 
 	// decoding request body
@@ -248,6 +253,9 @@ func (apiConfig *ApiConfig) HandleUpdateBlog(w http.ResponseWriter, r *http.Requ
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusOK, Response{
 		Title:        updateBlog.Title,
 		ContentURL:   updateBlog.ContentUrl,
@@ -257,12 +265,11 @@ func (apiConfig *ApiConfig) HandleUpdateBlog(w http.ResponseWriter, r *http.Requ
 		Tags:         updateBlog.Tags,
 		CreatedAt:    updatedBlog.CreatedAt,
 		UpdatedAt:    updatedBlog.UpdatedAt,
-		AccessToken:  newAccessToken,
 	})
 }
 
 // admin
-func (apiConfig *ApiConfig) HandleRemoveBlog(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveBlog(w http.ResponseWriter, r *http.Request) {
 	type RemoveBlogRequest struct {
 		ID uuid.UUID `json:"id"`
 	}
@@ -287,65 +294,231 @@ func (apiConfig *ApiConfig) HandleRemoveBlog(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusOK, EmptyResponse{
-		AccessToken: newAccessToken,
-	})
+	apiConfig.invalidateSearchSuggestions()
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
 // both
-func (apiConfig *ApiConfig) HandleGetBlogsByCategory(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
-	type Request struct {
-		CreatedAt time.Time `json:"createdAt"`
-		Category  string    `json:"category"`
-		Limit     int32     `json:"limit"`
-	}
-
+func (apiConfig *ApiConfig) HandleGetBlogsByCategory(w http.ResponseWriter, r *http.Request) {
 	type Response struct {
-		Blogs       []database.GetAllBlogsByCategoryRow `json:"blogs"`
-		AccessToken string                              `json:"accessToken"`
+		Blogs      []database.GetAllBlogsByCategoryRow `json:"blogs"`
+		NextCursor string                              `json:"next_cursor,omitempty"`
 	}
 
-	// decoding the request body
-	decoder := json.NewDecoder(r.Body)
-	params := Request{}
-	err := decoder.Decode(&params)
+	// extracting the category from the path and the page from the query
+	before := sql.NullTime{}
+	if value := r.URL.Query().Get("before"); value != "" {
+		parsedBefore, err := parseTimestamp(value)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, "invalid before timestamp")
+			return
+		}
+		before = parsedBefore
+	}
+	page, err := pagination.FromRequest[time.Time](r)
 	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// fetching all the blogs
-	categoryID, err := apiConfig.DB.GetCategoryIDByName(r.Context(), params.Category)
+	categoryID, err := apiConfig.DB.GetCategoryIDByName(r.Context(), r.PathValue("name"))
 	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	blogs, err := apiConfig.DB.GetAllBlogsByCategory(r.Context(), database.GetAllBlogsByCategoryParams{
-		Category:  categoryID,
-		CreatedAt: params.CreatedAt,
-		Limit:     params.Limit,
+		Category:       categoryID,
+		Before:         before,
+		AfterCreatedAt: afterCreatedAt(page),
+		AfterID:        page.AfterID(),
+		PageLimit:      page.FetchLimit(),
 	})
 	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	blogs, nextCursor := pagination.Trim(page, blogs, func(blog database.GetAllBlogsByCategoryRow) (time.Time, uuid.UUID) {
+		return blog.CreatedAt, blog.ID
+	})
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
-		Blogs:       blogs,
-		AccessToken: newAccessToken,
+		Blogs:      blogs,
+		NextCursor: nextCursor,
 	})
 }
 
-// both
-func (apiConfig *ApiConfig) HandleFilterBlogs(w http.ResponseWriter, r *http.Request, newAccessToken string) {
+// parseTimestamp reads an RFC 3339 query parameter for comparing it with a timestamp column,
+// the columns hold utc times without a time zone so the time is converted to utc first
+func parseTimestamp(value string) (sql.NullTime, error) {
+	parsedTime, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return sql.NullTime{}, err
+	}
 
+	return sql.NullTime{Time: parsedTime.UTC(), Valid: true}, nil
 }
 
 // both
-func (apiConfig *ApiConfig) HandleGetBlogByID(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
-	type Request struct {
-		ID uuid.UUID `json:"id"`
+func (apiConfig *ApiConfig) HandleFilterBlogs(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Blogs      []database.GetAllBlogsByCategoryRow `json:"blogs"`
+		NextCursor string                              `json:"next_cursor,omitempty"`
+	}
+
+	// extracting the filters from the query, every filter is optional
+	query := r.URL.Query()
+	filters := database.FilterBlogsByNewestParams{}
+	if category := query.Get("category"); category != "" {
+		filters.Category = sql.NullString{String: cases.Title(language.English).String(category), Valid: true}
+	}
+	if author := query.Get("author"); author != "" {
+		filters.Author = sql.NullString{String: strings.ToLower(author), Valid: true}
+	}
+	if value := query.Get("tags"); value != "" {
+		tags := strings.Split(value, ",")
+		switch query.Get("tags_match") {
+		case "", "any":
+			filters.AnyTags = tags
+		case "all":
+			filters.AllTags = tags
+		default:
+			utility.RespondWithError(w, http.StatusBadRequest, "tags_match must be any or all")
+			return
+		}
+	}
+	for name, target := range map[string]*sql.NullTime{"from": &filters.CreatedFrom, "to": &filters.CreatedTo} {
+		if value := query.Get(name); value != "" {
+			parsedTime, err := parseTimestamp(value)
+			if err != nil {
+				utility.RespondWithError(w, http.StatusBadRequest, "invalid "+name+" timestamp")
+				return
+			}
+			*target = parsedTime
+		}
 	}
+	if value := query.Get("has_code_repo"); value != "" {
+		hasCodeRepo, err := strconv.ParseBool(value)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, "has_code_repo must be true or false")
+			return
+		}
+		filters.HasCodeRepo = sql.NullBool{Bool: hasCodeRepo, Valid: true}
+	}
+
+	// fetching the blogs in the requested order, the cursor holds the sort key of that order
+	var blogs []database.GetAllBlogsByCategoryRow
+	var nextCursor string
+	switch query.Get("sort") {
+	case "", "newest":
+		page, err := pagination.FromRequest[time.Time](r)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		filters.AfterCreatedAt = afterCreatedAt(page)
+		filters.AfterID = page.AfterID()
+		filters.PageLimit = page.FetchLimit()
+
+		rows, err := apiConfig.DB.FilterBlogsByNewest(r.Context(), filters)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rows, nextCursor = pagination.Trim(page, rows, func(row database.FilterBlogsByNewestRow) (time.Time, uuid.UUID) {
+			return row.CreatedAt, row.ID
+		})
+		for _, row := range rows {
+			blogs = append(blogs, database.GetAllBlogsByCategoryRow(row))
+		}
+	case "views":
+		page, err := pagination.FromRequest[int32](r)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		afterViews, hasPrevious := page.AfterKey()
+
+		rows, err := apiConfig.DB.FilterBlogsByViews(r.Context(), database.FilterBlogsByViewsParams{
+			Category:    filters.Category,
+			AnyTags:     filters.AnyTags,
+			AllTags:     filters.AllTags,
+			Author:      filters.Author,
+			CreatedFrom: filters.CreatedFrom,
+			CreatedTo:   filters.CreatedTo,
+			HasCodeRepo: filters.HasCodeRepo,
+			AfterViews:  sql.NullInt32{Int32: afterViews, Valid: hasPrevious},
+			AfterID:     page.AfterID(),
+			PageLimit:   page.FetchLimit(),
+		})
+		if err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rows, nextCursor = pagination.Trim(page, rows, func(row database.FilterBlogsByViewsRow) (int32, uuid.UUID) {
+			return row.Views, row.ID
+		})
+		for _, row := range rows {
+			blogs = append(blogs, database.GetAllBlogsByCategoryRow(row))
+		}
+	case "likes":
+		page, err := pagination.FromRequest[int64](r)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		afterLikes, hasPrevious := page.AfterKey()
+
+		rows, err := apiConfig.DB.FilterBlogsByLikes(r.Context(), database.FilterBlogsByLikesParams{
+			Category:    filters.Category,
+			AnyTags:     filters.AnyTags,
+			AllTags:     filters.AllTags,
+			Author:      filters.Author,
+			CreatedFrom: filters.CreatedFrom,
+			CreatedTo:   filters.CreatedTo,
+			HasCodeRepo: filters.HasCodeRepo,
+			AfterLikes:  sql.NullInt64{Int64: afterLikes, Valid: hasPrevious},
+			AfterID:     page.AfterID(),
+			PageLimit:   page.FetchLimit(),
+		})
+		if err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		rows, nextCursor = pagination.Trim(page, rows, func(row database.FilterBlogsByLikesRow) (int64, uuid.UUID) {
+			return row.LikeCount, row.ID
+		})
+		for _, row := range rows {
+			blogs = append(blogs, database.GetAllBlogsByCategoryRow{
+				ID:           row.ID,
+				Title:        row.Title,
+				Brief:        row.Brief,
+				ThumbnailUrl: row.ThumbnailUrl,
+				Views:        row.Views,
+				Tags:         row.Tags,
+				CreatedAt:    row.CreatedAt,
+			})
+		}
+	default:
+		utility.RespondWithError(w, http.StatusBadRequest, "sort must be newest, views or likes")
+		return
+	}
+
+	if blogs == nil {
+		blogs = make([]database.GetAllBlogsByCategoryRow, 0)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Blogs:      blogs,
+		NextCursor: nextCursor,
+	})
+}
+
+// both
+func (apiConfig *ApiConfig) HandleGetBlogByID(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
 
 	type Response struct {
 		Title        string            `json:"title"`
@@ -357,50 +530,40 @@ func (apiConfig *ApiConfig) HandleGetBlogByID(w http.ResponseWriter, r *http.Req
 		Tags         []string          `json:"tags"`
 		Author       string            `json:"author"`
 		CreatedAt    time.Time         `json:"createdAt"`
-		HasUserLiked bool              `json:"hasUserLiked"`
-		AccessToken  string            `json:"accessToken"`
+		HasUserLiked *bool             `json:"hasUserLiked,omitempty"`
 	}
 
-	// decoding request body
-	decoder := json.NewDecoder(r.Body)
-	params := Request{}
-	err := decoder.Decode(&params)
-	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	if params.ID == uuid.Nil {
+	// extracting blog id from the path
+	blogID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || blogID == uuid.Nil {
 		utility.RespondWithError(w, http.StatusBadRequest, "invalid blog id")
 		return
 	}
 
-	blog, err := apiConfig.DB.GetBlogByID(r.Context(), params.ID)
+	blog, err := apiConfig.DB.GetBlogByID(r.Context(), blogID)
 	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	// blogs hidden after being reported are not shown until the reports are dismissed
+	if blog.Hidden {
+		utility.RespondWithError(w, http.StatusNotFound, "blog not found")
+		return
+	}
+
 	var images map[string]string
 	if err = json.Unmarshal(blog.Images, &images); err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	// fetching number of likes for the blog and checking if user has liked the blog or not
-	noOfLikes, err := apiConfig.DB.GetNumberOfLikes(r.Context(), params.ID)
+	// fetching number of likes for the blog
+	noOfLikes, err := apiConfig.DB.GetNumberOfLikes(r.Context(), blogID)
 	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	hasUserLikedThisBlog, err := apiConfig.DB.HasUserLikedBlog(r.Context(), database.HasUserLikedBlogParams{
-		UserID: IDAndRole.ID,
-		BlogID: params.ID,
-	})
-	if err != nil && err != sql.ErrNoRows {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
-	}
 
 	response := Response{
 		Title:        blog.Title,
@@ -412,26 +575,36 @@ func (apiConfig *ApiConfig) HandleGetBlogByID(w http.ResponseWriter, r *http.Req
 		Tags:         blog.Tags,
 		Author:       blog.Username,
 		CreatedAt:    blog.CreatedAt,
-		AccessToken:  newAccessToken,
 	}
-	if hasUserLikedThisBlog == 1 {
-		response.HasUserLiked = true
-	} else {
-		response.HasUserLiked = false
+
+	// checking if user has liked the blog or not, anonymous readers get no like status
+	if IDAndRole != nil {
+		hasUserLikedThisBlog, err := apiConfig.DB.HasUserLikedBlog(r.Context(), database.HasUserLikedBlogParams{
+			UserID: IDAndRole.ID,
+			BlogID: blogID,
+		})
+		if err != nil && err != sql.ErrNoRows {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hasUserLiked := hasUserLikedThisBlog == 1
+		response.HasUserLiked = &hasUserLiked
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, response)
 }
 
 // user
-func (apiConfig *ApiConfig) HandleLikeOrDislike(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleLikeOrDislike(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		ID uuid.UUID `json:"id"`
 	}
 
 	type Response struct {
-		LikesCount  int64  `json:"likesCount"`
-		AccessToken string `json:"accessToken"`
+		LikesCount int64 `json:"likesCount"`
 	}
 
 	// decoding request body
@@ -485,20 +658,18 @@ func (apiConfig *ApiConfig) HandleLikeOrDislike(w http.ResponseWriter, r *http.R
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
-		LikesCount:  likesCount,
-		AccessToken: newAccessToken,
+		LikesCount: likesCount,
 	})
 }
 
 // user
-func (apiConfig *ApiConfig) HandleIncrementView(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleIncrementView(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		ID uuid.UUID `json:"id"`
 	}
 
 	type Response struct {
-		Views       int32  `json:"views"`
-		AccessToken string `json:"accessToken"`
+		Views int32 `json:"views"`
 	}
 
 	// decoding request body
@@ -527,7 +698,6 @@ func (apiConfig *ApiConfig) HandleIncrementView(w http.ResponseWriter, r *http.R
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
-		Views:       views,
-		AccessToken: newAccessToken,
+		Views: views,
 	})
 }
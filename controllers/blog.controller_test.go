@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	expected := time.Date(2023, 12, 31, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"utc", "2023-12-31T18:30:00Z"},
+		{"positive_offset", "2024-01-01T00:00:00+05:30"},
+		{"negative_offset", "2023-12-31T13:30:00-05:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsedTime, err := parseTimestamp(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !parsedTime.Valid || parsedTime.Time != expected {
+				t.Errorf("got %v, want %v", parsedTime.Time, expected)
+			}
+		})
+	}
+
+	if _, err := parseTimestamp("2024-01-01"); err == nil {
+		t.Error("timestamp without a time accepted")
+	}
+}
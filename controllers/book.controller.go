@@ -3,14 +3,16 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 )
 
 // admin
-func (apiConfig *ApiConfig) HandleAddBook(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleAddBook(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Name          string   `json:"name"`
 		CoverImageURL string   `json:"coverImageUrl"`
@@ -20,9 +22,8 @@ func (apiConfig *ApiConfig) HandleAddBook(w http.ResponseWriter, r *http.Request
 	}
 
 	type Response struct {
-		ID          uuid.UUID `json:"id"`
-		Book        Request   `json:"book"`
-		AccessToken string    `json:"accessToken"`
+		ID   uuid.UUID `json:"id"`
+		Book Request   `json:"book"`
 	}
 
 	// decoding request body
@@ -73,6 +74,8 @@ func (apiConfig *ApiConfig) HandleAddBook(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusCreated, Response{
 		ID: newBook.ID,
 		Book: Request{
@@ -82,12 +85,11 @@ func (apiConfig *ApiConfig) HandleAddBook(w http.ResponseWriter, r *http.Request
 			Tags:          newBook.Tags,
 			Level:         params.Level,
 		},
-		AccessToken: newAccessToken,
 	})
 }
 
 // admin
-func (apiConfig *ApiConfig) HandleUpdateBook(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateBook(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		ID            uuid.UUID `json:"id"`
 		Name          string    `json:"name,omitempty"`
@@ -157,9 +159,10 @@ func (apiConfig *ApiConfig) HandleUpdateBook(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusOK, struct {
-		Book        Request `json:"book"`
-		AccessToken string  `json:"accessToken"`
+		Book Request `json:"book"`
 	}{
 		Book: Request{
 			ID:            params.ID,
@@ -169,12 +172,11 @@ func (apiConfig *ApiConfig) HandleUpdateBook(w http.ResponseWriter, r *http.Requ
 			Tags:          params.Tags,
 			Level:         params.Level,
 		},
-		AccessToken: newAccessToken,
 	})
 }
 
 // admin
-func (apiConfig *ApiConfig) HandleRemoveBook(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveBook(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		ID uuid.UUID `json:"id"`
 	}
@@ -194,13 +196,13 @@ func (apiConfig *ApiConfig) HandleRemoveBook(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusOK, EmptyResponse{
-		AccessToken: newAccessToken,
-	})
+	apiConfig.invalidateSearchSuggestions()
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
 // user
-func (apiConfig *ApiConfig) HandleFilterBooksByLevel(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleFilterBooksByLevel(w http.ResponseWriter, r *http.Request) {
 	// extracting level from query params
 	level := r.URL.Query().Get("level")
 	if level == "" {
@@ -209,70 +211,87 @@ func (apiConfig *ApiConfig) HandleFilterBooksByLevel(w http.ResponseWriter, r *h
 		return
 	}
 
+	page, err := pagination.FromRequest[time.Time](r)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// filtering the books according to level
-	filteredBooks, err := apiConfig.DB.GetBooksByLevel(r.Context(), level)
+	filteredBooks, err := apiConfig.DB.GetBooksByLevel(r.Context(), database.GetBooksByLevelParams{
+		Level:          level,
+		AfterCreatedAt: afterCreatedAt(page),
+		AfterID:        page.AfterID(),
+		PageLimit:      page.FetchLimit(),
+	})
 	if err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	filteredBooks, nextCursor := pagination.Trim(page, filteredBooks, func(book database.GetBooksByLevelRow) (time.Time, uuid.UUID) {
+		return book.CreatedAt, book.ID
+	})
 
 	type FilteredBooksResponse struct {
-		Books       []database.GetBooksByLevelRow `json:"books"`
-		AccessToken string                        `json:"accessToken"`
+		Books      []database.GetBooksByLevelRow `json:"books"`
+		NextCursor string                        `json:"next_cursor,omitempty"`
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, FilteredBooksResponse{
-		Books:       filteredBooks,
-		AccessToken: newAccessToken,
+		Books:      filteredBooks,
+		NextCursor: nextCursor,
 	})
 }
 
 // user
-func (apiConfig *ApiConfig) HandleGetAllBooks(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleGetAllBooks(w http.ResponseWriter, r *http.Request) {
 	type Response struct {
-		Books       []database.GetAllBooksRow `json:"books"`
-		AccessToken string                    `json:"accessToken"`
+		Books      []database.GetAllBooksRow `json:"books"`
+		NextCursor string                    `json:"next_cursor,omitempty"`
+	}
+
+	// extracting the page from the query
+	page, err := pagination.FromRequest[time.Time](r)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	books, err := apiConfig.DB.GetAllBooks(r.Context())
+	books, err := apiConfig.DB.GetAllBooks(r.Context(), database.GetAllBooksParams{
+		AfterCreatedAt: afterCreatedAt(page),
+		AfterID:        page.AfterID(),
+		PageLimit:      page.FetchLimit(),
+	})
 	if err != nil {
 		utility.RespondWithError(w, http.StatusNotFound, err.Error())
 		return
 	}
+	books, nextCursor := pagination.Trim(page, books, func(book database.GetAllBooksRow) (time.Time, uuid.UUID) {
+		return book.CreatedAt, book.ID
+	})
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
-		Books:       books,
-		AccessToken: newAccessToken,
+		Books:      books,
+		NextCursor: nextCursor,
 	})
 }
 
 // user
-func (apiConfig *ApiConfig) HandleGetReviewByBookID(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
-	type ReviewRequest struct {
-		ID uuid.UUID `json:"id"`
-	}
-
+func (apiConfig *ApiConfig) HandleGetReviewByBookID(w http.ResponseWriter, r *http.Request) {
 	type ReviewResposne struct {
 		CoverImageURL string `json:"coverImageUrl"`
 		Review        string `json:"review"`
-		AccessToken   string `json:"accessToken"`
 	}
 
-	// decoding request body
-	decoder := json.NewDecoder(r.Body)
-	params := ReviewRequest{}
-	err := decoder.Decode(&params)
-	if err != nil {
-		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	if params.ID == uuid.Nil {
+	// extracting book id from the path
+	bookID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || bookID == uuid.Nil {
 		utility.RespondWithError(w, http.StatusBadRequest, "invalid book id")
 		return
 	}
 
 	// fetching the review
-	review, err := apiConfig.DB.GetReviewByBookID(r.Context(), params.ID)
+	review, err := apiConfig.DB.GetReviewByBookID(r.Context(), bookID)
 	if err != nil {
 		utility.RespondWithError(w, http.StatusNotFound, err.Error())
 		return
@@ -281,6 +300,5 @@ func (apiConfig *ApiConfig) HandleGetReviewByBookID(w http.ResponseWriter, r *ht
 	utility.RespondWithJson(w, http.StatusOK, ReviewResposne{
 		CoverImageURL: review.CoverImageUrl,
 		Review:        review.Review,
-		AccessToken:   newAccessToken,
 	})
 }
@@ -12,13 +12,12 @@ import (
 
 // response struct
 type categoryResponse struct {
-	Category    string    `json:"category"`
-	AccessToken string    `json:"accessToken"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-func (apiConfig *ApiConfig) HandleCreateCategory(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleCreateCategory(w http.ResponseWriter, r *http.Request) {
 	// request struct
 	type createCategoryRequest struct {
 		Category string `json:"category"`
@@ -43,15 +42,16 @@ func (apiConfig *ApiConfig) HandleCreateCategory(w http.ResponseWriter, r *http.
 		return
 	}
 
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusCreated, categoryResponse{
-		Category:    newCategory.Category,
-		AccessToken: newAccessToken,
-		CreatedAt:   newCategory.CreatedAt,
-		UpdatedAt:   newCategory.UpdatedAt,
+		Category:  newCategory.Category,
+		CreatedAt: newCategory.CreatedAt,
+		UpdatedAt: newCategory.UpdatedAt,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleUpdateCategory(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateCategory(w http.ResponseWriter, r *http.Request) {
 	// request struct
 	type updateCategoryRequest struct {
 		CategoryID uuid.UUID `json:"categoryID"`
@@ -85,15 +85,16 @@ func (apiConfig *ApiConfig) HandleUpdateCategory(w http.ResponseWriter, r *http.
 		return
 	}
 
+	apiConfig.invalidateSearchSuggestions()
+
 	utility.RespondWithJson(w, http.StatusOK, categoryResponse{
-		Category:    updatedCategory.Category,
-		AccessToken: newAccessToken,
-		CreatedAt:   updatedCategory.CreatedAt,
-		UpdatedAt:   updatedCategory.UpdatedAt,
+		Category:  updatedCategory.Category,
+		CreatedAt: updatedCategory.CreatedAt,
+		UpdatedAt: updatedCategory.UpdatedAt,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleRemoveCategory(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveCategory(w http.ResponseWriter, r *http.Request) {
 	// request struct
 	type removeCategoryRequest struct {
 		CategoryID uuid.UUID `json:"categoryID"`
@@ -118,16 +119,15 @@ func (apiConfig *ApiConfig) HandleRemoveCategory(w http.ResponseWriter, r *http.
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusOK, EmptyResponse{
-		AccessToken: newAccessToken,
-	})
+	apiConfig.invalidateSearchSuggestions()
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
-func (apiConfig *ApiConfig) HandleGetAllCategories(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleGetAllCategories(w http.ResponseWriter, r *http.Request) {
 	// response struct
 	type AllCategories struct {
-		Categories  []database.Category
-		AccessToken string `json:"accessToken"`
+		Categories []database.Category
 	}
 
 	allCategories, err := apiConfig.DB.GetAllCategories(r.Context())
@@ -137,7 +137,6 @@ func (apiConfig *ApiConfig) HandleGetAllCategories(w http.ResponseWriter, r *htt
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, AllCategories{
-		Categories:  allCategories,
-		AccessToken: newAccessToken,
+		Categories: allCategories,
 	})
 }
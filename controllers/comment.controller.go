@@ -1,28 +1,38 @@
 package controllers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/contentfilter"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 )
 
 // user
-func (apiConfig *ApiConfig) HandleCreateComment(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		BlogID      uuid.UUID `json:"blogID"`
+		ParentID    uuid.UUID `json:"parentID"`
 		Description string    `json:"description"`
 	}
 
 	type Response struct {
-		ID          uuid.UUID `json:"id"`
-		Description string    `json:"description"`
-		CreatedAt   time.Time `json:"createdAt"`
-		UpdatedAt   time.Time `json:"updatedAt"`
-		AccessToken string    `json:"accessToken"`
+		ID          uuid.UUID  `json:"id"`
+		ParentID    *uuid.UUID `json:"parentID,omitempty"`
+		Depth       int32      `json:"depth"`
+		Status      string     `json:"status"`
+		Description string     `json:"description"`
+		CreatedAt   time.Time  `json:"createdAt"`
+		UpdatedAt   time.Time  `json:"updatedAt"`
 	}
 
 	// decoding request body
@@ -44,28 +54,97 @@ func (apiConfig *ApiConfig) HandleCreateComment(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// adding new comment
-	newComment, err := apiConfig.DB.CreateComment(r.Context(), database.CreateCommentParams{
+	// hidden blogs are not shown so they can not be commented on either
+	blog, err := apiConfig.DB.GetBlogHideState(r.Context(), params.BlogID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && blog.Hidden) {
+		utility.RespondWithError(w, http.StatusNotFound, "blog not found")
+		return
+	}
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// running the comment through the content filter
+	filterResult, err := apiConfig.ContentFilter.Check(r.Context(), contentfilter.Comment{
+		UserID:      IDAndRole.ID,
+		Description: params.Description,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if filterResult.Verdict == contentfilter.VerdictReject {
+		respondWithRejectedComment(w, filterResult.Reasons)
+		return
+	}
+
+	// checking that a reply is on the same blog and within the maximum nesting depth
+	newCommentParams := database.CreateCommentParams{
 		Description: params.Description,
 		UserID:      IDAndRole.ID,
 		BlogID:      params.BlogID,
-	})
+	}
+	if params.ParentID != uuid.Nil {
+		parent, err := apiConfig.DB.GetCommentThreadInfo(r.Context(), params.ParentID)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusNotFound, "parent comment not found")
+			return
+		}
+		if parent.BlogID != params.BlogID {
+			utility.RespondWithError(w, http.StatusBadRequest, "parent comment belongs to another blog")
+			return
+		}
+		if parent.Status != commentStatusApproved || parent.DeletedAt.Valid {
+			utility.RespondWithError(w, http.StatusBadRequest, "parent comment can not be replied to")
+			return
+		}
+		if int(parent.Depth)+1 > apiConfig.Comments.withDefaults().MaxDepth {
+			utility.RespondWithError(w, http.StatusBadRequest, "maximum reply depth reached")
+			return
+		}
+		newCommentParams.ParentID = uuid.NullUUID{UUID: params.ParentID, Valid: true}
+		newCommentParams.Depth = parent.Depth + 1
+	}
+
+	// deciding whether the comment is published right away or waits for moderation
+	// borderline comments always wait for moderation
+	newCommentParams.Status, err = apiConfig.initialCommentStatus(r.Context(), IDAndRole)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if filterResult.Verdict == contentfilter.VerdictReview {
+		newCommentParams.Status = commentStatusPending
+	}
+
+	// adding new comment
+	newComment, err := apiConfig.DB.CreateComment(r.Context(), newCommentParams)
 	if err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusCreated, Response{
+	response := Response{
 		ID:          newComment.ID,
+		Depth:       newComment.Depth,
+		Status:      newComment.Status,
 		Description: newComment.Description,
 		CreatedAt:   newComment.CreatedAt,
 		UpdatedAt:   newComment.UpdatedAt,
-		AccessToken: newAccessToken,
-	})
+	}
+	if newComment.ParentID.Valid {
+		response.ParentID = &newComment.ParentID.UUID
+	}
+
+	utility.RespondWithJson(w, http.StatusCreated, response)
 }
 
 // user
-func (apiConfig *ApiConfig) HandleUpdateComment(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		ID          uuid.UUID `json:"id"`
 		Description string    `json:"description"`
@@ -73,8 +152,8 @@ func (apiConfig *ApiConfig) HandleUpdateComment(w http.ResponseWriter, r *http.R
 
 	type Response struct {
 		Description string    `json:"description"`
+		Status      string    `json:"status"`
 		UpdatedAt   time.Time `json:"updatedAt"`
-		AccessToken string    `json:"accessToken"`
 	}
 
 	// decoding request body
@@ -97,8 +176,28 @@ func (apiConfig *ApiConfig) HandleUpdateComment(w http.ResponseWriter, r *http.R
 	}
 
 	// updating the comment
+	// running the new description through the content filter, borderline edits go back to moderation
+	filterResult, err := apiConfig.ContentFilter.Check(r.Context(), contentfilter.Comment{
+		UserID:      IDAndRole.ID,
+		CommentID:   params.ID,
+		Description: params.Description,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if filterResult.Verdict == contentfilter.VerdictReject {
+		respondWithRejectedComment(w, filterResult.Reasons)
+		return
+	}
+	status := sql.NullString{}
+	if filterResult.Verdict == contentfilter.VerdictReview {
+		status = sql.NullString{String: commentStatusPending, Valid: true}
+	}
+
 	updatedComment, err := apiConfig.DB.UpdateCommentByID(r.Context(), database.UpdateCommentByIDParams{
 		Description: params.Description,
+		Status:      status,
 		ID:          params.ID,
 		UserID:      IDAndRole.ID,
 	})
@@ -109,13 +208,16 @@ func (apiConfig *ApiConfig) HandleUpdateComment(w http.ResponseWriter, r *http.R
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
 		Description: updatedComment.Description,
+		Status:      updatedComment.Status,
 		UpdatedAt:   updatedComment.UpdatedAt,
-		AccessToken: newAccessToken,
 	})
 }
 
 // user
-func (apiConfig *ApiConfig) HandleRemoveComment(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveComment(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		ID uuid.UUID `json:"id"`
 	}
@@ -134,29 +236,129 @@ func (apiConfig *ApiConfig) HandleRemoveComment(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// removing comment
-	if err = apiConfig.DB.RemoveComment(r.Context(), database.RemoveCommentParams{
+	// removing comment, it stays in its thread rendered as removed
+	removedComments, err := apiConfig.DB.RemoveComment(r.Context(), database.RemoveCommentParams{
 		ID:     params.ID,
 		UserID: IDAndRole.ID,
-	}); err != nil {
+	})
+	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	if removedComments == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "comment not found")
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
 
-	utility.RespondWithJson(w, http.StatusOK, EmptyResponse{
-		AccessToken: newAccessToken,
+// user
+func (apiConfig *ApiConfig) HandleGetAllCommentsByBlogID(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Comments   []threadedComment `json:"comments"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+	}
+
+	// extracting blog id from the path
+	blogID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil || blogID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid blog id")
+		return
+	}
+
+	// the viewer is only known on authenticated requests
+	viewerID := uuid.NullUUID{}
+	if IDAndRole := IDAndRoleFromContext(r.Context()); IDAndRole != nil {
+		viewerID = uuid.NullUUID{UUID: IDAndRole.ID, Valid: true}
+	}
+
+	// top level comments are paged from the oldest to the newest or from the most to the least liked
+	var topLevelComments []database.GetCommentByBlogIDRow
+	var nextCursor string
+	switch r.URL.Query().Get("sort") {
+	case "", "oldest":
+		page, err := pagination.FromRequest[time.Time](r)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		topLevelComments, err = apiConfig.DB.GetCommentByBlogID(r.Context(), database.GetCommentByBlogIDParams{
+			ViewerID:       viewerID,
+			BlogID:         blogID,
+			AfterCreatedAt: afterCreatedAt(page),
+			AfterID:        page.AfterID(),
+			PageLimit:      page.FetchLimit(),
+		})
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		topLevelComments, nextCursor = pagination.Trim(page, topLevelComments, func(comment database.GetCommentByBlogIDRow) (time.Time, uuid.UUID) {
+			return comment.CreatedAt, comment.ID
+		})
+	case "top":
+		page, err := pagination.FromRequest[int32](r)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		afterLikes, hasPrevious := page.AfterKey()
+
+		topComments, err := apiConfig.DB.GetTopCommentsByBlogID(r.Context(), database.GetTopCommentsByBlogIDParams{
+			ViewerID:   viewerID,
+			BlogID:     blogID,
+			AfterLikes: sql.NullInt32{Int32: afterLikes, Valid: hasPrevious},
+			AfterID:    page.AfterID(),
+			PageLimit:  page.FetchLimit(),
+		})
+		if err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		topComments, nextCursor = pagination.Trim(page, topComments, func(comment database.GetTopCommentsByBlogIDRow) (int32, uuid.UUID) {
+			return comment.LikesCount, comment.ID
+		})
+		for _, comment := range topComments {
+			topLevelComments = append(topLevelComments, database.GetCommentByBlogIDRow(comment))
+		}
+	default:
+		utility.RespondWithError(w, http.StatusBadRequest, "sort must be oldest or top")
+		return
+	}
+
+	// fetching every reply in the threads of this page
+	rootIDs := make([]uuid.UUID, 0, len(topLevelComments))
+	for _, comment := range topLevelComments {
+		rootIDs = append(rootIDs, comment.ID)
+	}
+	replies, err := apiConfig.DB.GetCommentRepliesByRootIDs(r.Context(), database.GetCommentRepliesByRootIDsParams{
+		ViewerID: viewerID,
+		RootIds:  rootIDs,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Comments:   flattenThreads(topLevelComments, replies, viewerID.Valid),
+		NextCursor: nextCursor,
 	})
 }
 
 // user
-func (apiConfig *ApiConfig) HandleGetAllCommentsByBlogID(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleLikeOrDislikeComment(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type Request struct {
 		ID uuid.UUID `json:"id"`
 	}
 
 	type Response struct {
-		Comments    []database.GetCommentByBlogIDRow `json:"comments"`
-		AccessToken string                           `json:"accessToken"`
+		LikesCount int32 `json:"likesCount"`
 	}
 
 	// decoding request body
@@ -169,18 +371,44 @@ func (apiConfig *ApiConfig) HandleGetAllCommentsByBlogID(w http.ResponseWriter,
 	}
 
 	if params.ID == uuid.Nil {
-		utility.RespondWithError(w, http.StatusBadRequest, "invalid blog id")
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid comment id")
 		return
 	}
 
-	allComments, err := apiConfig.DB.GetCommentByBlogID(r.Context(), params.ID)
+	// checking if comment is liked then disliking it
+	isCommentLiked, err := apiConfig.DB.HasUserLikedComment(r.Context(), database.HasUserLikedCommentParams{
+		UserID:    IDAndRole.ID,
+		CommentID: params.ID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the like and dislike queries return the updated likes count of the comment
+	var likesCount int32
+	if isCommentLiked {
+		likesCount, err = apiConfig.DB.DislikeComment(r.Context(), database.DislikeCommentParams{
+			UserID:    IDAndRole.ID,
+			CommentID: params.ID,
+		})
+	} else {
+		likesCount, err = apiConfig.DB.LikeComment(r.Context(), database.LikeCommentParams{
+			UserID:    IDAndRole.ID,
+			CommentID: params.ID,
+		})
+	}
+	// only comments shown on the blog can be liked, pending, rejected and deleted ones are not found
+	if errors.Is(err, sql.ErrNoRows) {
+		utility.RespondWithError(w, http.StatusNotFound, "comment not found")
+		return
+	}
 	if err != nil {
 		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, Response{
-		Comments:    allComments,
-		AccessToken: newAccessToken,
+		LikesCount: likesCount,
 	})
 }
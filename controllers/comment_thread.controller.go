@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+// number of approved comments after which the comments of a user skip the moderation queue
+// when nothing else is configured
+const DefaultCommentAutoApproveAfter = 3
+
+// comment options, a zero MaxDepth is replaced by the default
+type CommentConfig struct {
+	// deepest level a reply can be nested at, top level comments are at depth 0
+	MaxDepth int
+
+	// number of approved comments after which the comments of a user skip the moderation
+	// queue, 0 approves every comment right away
+	AutoApproveAfter int
+}
+
+func (config CommentConfig) withDefaults() CommentConfig {
+	if config.MaxDepth <= 0 {
+		config.MaxDepth = 5
+	}
+
+	return config
+}
+
+// comment of a blog in thread order, every comment is followed by its replies,
+// removed comments keep their place without their description and author
+type threadedComment struct {
+	ID            uuid.UUID
+	ParentID      uuid.NullUUID
+	Description   string
+	Username      string
+	ProfilePicUrl string
+	Depth         int32
+	ReplyCount    int64
+	Removed       bool
+	LikesCount    int32 `json:"likesCount"`
+	HasUserLiked  *bool `json:"hasUserLiked,omitempty"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// flattenThreads lists every top level comment followed by its replies depth first,
+// the replies of a comment are ordered from the oldest to the newest. Whether the
+// viewer liked a comment is only included for authenticated viewers
+func flattenThreads(roots []database.GetCommentByBlogIDRow, replies []database.GetCommentRepliesByRootIDsRow, authenticated bool) []threadedComment {
+	hasUserLiked := func(liked bool) *bool {
+		if !authenticated {
+			return nil
+		}
+		return &liked
+	}
+
+	// grouping the replies by the comment they reply to, they are already ordered by creation
+	repliesByParent := make(map[uuid.UUID][]database.GetCommentRepliesByRootIDsRow)
+	for _, reply := range replies {
+		repliesByParent[reply.ParentID.UUID] = append(repliesByParent[reply.ParentID.UUID], reply)
+	}
+
+	comments := make([]threadedComment, 0, len(roots)+len(replies))
+	var appendReplies func(parentID uuid.UUID)
+	appendReplies = func(parentID uuid.UUID) {
+		for _, reply := range repliesByParent[parentID] {
+			comments = append(comments, threadedComment{
+				ID:            reply.ID,
+				ParentID:      reply.ParentID,
+				Description:   reply.Description,
+				Username:      reply.Username,
+				ProfilePicUrl: reply.ProfilePicUrl,
+				Depth:         reply.Depth,
+				ReplyCount:    reply.ReplyCount,
+				Removed:       reply.Removed,
+				LikesCount:    reply.LikesCount,
+				HasUserLiked:  hasUserLiked(reply.HasUserLiked),
+				CreatedAt:     reply.CreatedAt,
+				UpdatedAt:     reply.UpdatedAt,
+			})
+			appendReplies(reply.ID)
+		}
+	}
+
+	for _, root := range roots {
+		comments = append(comments, threadedComment{
+			ID:            root.ID,
+			Description:   root.Description,
+			Username:      root.Username,
+			ProfilePicUrl: root.ProfilePicUrl,
+			ReplyCount:    root.ReplyCount,
+			Removed:       root.Removed,
+			LikesCount:    root.LikesCount,
+			HasUserLiked:  hasUserLiked(root.HasUserLiked),
+			CreatedAt:     root.CreatedAt,
+			UpdatedAt:     root.UpdatedAt,
+		})
+		appendReplies(root.ID)
+	}
+
+	return comments
+}
@@ -4,19 +4,34 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/cache"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/contentfilter"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/search"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
 )
 
 type ApiConfig struct {
 	DB            *database.Queries
-	JwtSecret     string
+	TokenManager  *token.Manager
 	OtpCache      *cache.OtpCache
 	DataValidator *validator.Validate
+	LoginThrottle LoginThrottleConfig
+	Comments      CommentConfig
+	ContentFilter contentfilter.ContentFilter
+	Reports       ReportConfig
+	SuggestCache  *search.SuggestCache
+}
+
+// invalidateSearchSuggestions has to be called whenever blogs, books or categories
+// change, the cached search suggestions may include the changed content
+func (apiConfig *ApiConfig) invalidateSearchSuggestions() {
+	apiConfig.SuggestCache.Invalidate()
 }
 
 type IDAndRole struct {
-	ID   uuid.UUID `json:"id"`
-	Role string    `json:"role"`
+	ID        uuid.UUID `json:"id"`
+	Role      string    `json:"role"`
+	SessionID uuid.UUID `json:"session_id"`
 }
 
 type registrationRequest struct {
@@ -34,16 +49,14 @@ type VerifyOTPRequest struct {
 }
 
 type loginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	DeviceName string `json:"device_name"`
 }
 
 type loginResponse struct {
 	Username      string `json:"username"`
 	ProfilePicUrl string `json:"profile_pic_url"`
 	AccessToken   string `json:"access_token"`
-}
-
-type EmptyResponse struct {
-	AccessToken string `json:"access_token"`
+	RefreshToken  string `json:"refresh_token"`
 }
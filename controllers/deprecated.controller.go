@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+)
+
+// the handlers below keep the old GET endpoints which took their parameters in a json body
+// working for one more release, they translate the body into the path values and query
+// of the new endpoints and point the client to them
+
+// marks the response as coming from a deprecated endpoint replaced by successor
+func markDeprecated(w http.ResponseWriter, successor string) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+}
+
+// decodes the id from the request body of the deprecated endpoints
+func decodeDeprecatedID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	type Request struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	if err := decoder.Decode(&params); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return uuid.Nil, false
+	}
+
+	return params.ID, true
+}
+
+// Deprecated: use GET /api/v1/blogs/{id}
+func (apiConfig *ApiConfig) HandleGetBlogByIDFromBody(w http.ResponseWriter, r *http.Request) {
+	blogID, ok := decodeDeprecatedID(w, r)
+	if !ok {
+		return
+	}
+
+	markDeprecated(w, "/api/v1/blogs/"+blogID.String())
+	r.SetPathValue("id", blogID.String())
+	apiConfig.HandleGetBlogByID(w, r)
+}
+
+// Deprecated: use GET /api/v1/blogs/{id}/comments
+func (apiConfig *ApiConfig) HandleGetAllCommentsByBlogIDFromBody(w http.ResponseWriter, r *http.Request) {
+	blogID, ok := decodeDeprecatedID(w, r)
+	if !ok {
+		return
+	}
+
+	markDeprecated(w, "/api/v1/blogs/"+blogID.String()+"/comments")
+	r.SetPathValue("id", blogID.String())
+	apiConfig.HandleGetAllCommentsByBlogID(w, r)
+}
+
+// Deprecated: use GET /api/v1/books/{id}/review
+func (apiConfig *ApiConfig) HandleGetReviewByBookIDFromBody(w http.ResponseWriter, r *http.Request) {
+	bookID, ok := decodeDeprecatedID(w, r)
+	if !ok {
+		return
+	}
+
+	markDeprecated(w, "/api/v1/books/"+bookID.String()+"/review")
+	r.SetPathValue("id", bookID.String())
+	apiConfig.HandleGetReviewByBookID(w, r)
+}
+
+// Deprecated: use GET /api/v1/categories/{name}/blogs?before=&limit=
+func (apiConfig *ApiConfig) HandleGetBlogsByCategoryFromBody(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		CreatedAt time.Time `json:"createdAt"`
+		Category  string    `json:"category"`
+		Limit     int32     `json:"limit"`
+	}
+
+	// decoding the request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	query := url.Values{}
+	if !params.CreatedAt.IsZero() {
+		query.Set("before", params.CreatedAt.Format(time.RFC3339))
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(int(params.Limit)))
+	}
+
+	successor := "/api/v1/categories/" + url.PathEscape(params.Category) + "/blogs"
+	if len(query) > 0 {
+		successor += "?" + query.Encode()
+	}
+	markDeprecated(w, successor)
+
+	r.SetPathValue("name", params.Category)
+	r.URL.RawQuery = query.Encode()
+	apiConfig.HandleGetBlogsByCategory(w, r)
+}
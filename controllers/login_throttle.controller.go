@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	loginScopeAccount = "account"
+	loginScopeIP      = "ip"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// compared against when the email is not registered so that the response takes as long as for a wrong password
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// login throttling options, zero values are replaced by the defaults
+type LoginThrottleConfig struct {
+	// failed logins after which an account or ip is locked
+	MaxAccountFailures int
+	MaxIPFailures      int
+
+	// failures older than this window are forgotten
+	FailureWindow time.Duration
+
+	// the lockout starts at BaseLockout and doubles with every further failure up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+func (config LoginThrottleConfig) withDefaults() LoginThrottleConfig {
+	if config.MaxAccountFailures <= 0 {
+		config.MaxAccountFailures = 5
+	}
+	if config.MaxIPFailures <= 0 {
+		config.MaxIPFailures = 20
+	}
+	if config.FailureWindow <= 0 {
+		config.FailureWindow = time.Hour
+	}
+	if config.BaseLockout <= 0 {
+		config.BaseLockout = time.Minute
+	}
+	if config.MaxLockout <= 0 {
+		config.MaxLockout = time.Hour
+	}
+
+	return config
+}
+
+// lockout duration for the number of failures, nothing if the limit is not reached yet
+func (config LoginThrottleConfig) lockoutFor(failures int, maxFailures int) time.Duration {
+	if failures < maxFailures {
+		return 0
+	}
+
+	lockout := config.BaseLockout
+	for i := maxFailures; i < failures && lockout < config.MaxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, config.MaxLockout)
+}
+
+// address of the client the request came from
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// returns the time until which logins for the email or from the ip are locked, zero if they are not
+func (apiConfig *ApiConfig) loginLockedUntil(ctx context.Context, email string, ip string) (time.Time, error) {
+	var lockedUntil time.Time
+	for scope, identifier := range map[string]string{loginScopeAccount: email, loginScopeIP: ip} {
+		until, err := apiConfig.DB.GetLoginLockout(ctx, database.GetLoginLockoutParams{
+			Scope:      scope,
+			Identifier: identifier,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return time.Time{}, err
+		}
+		if until.Valid && until.Time.After(lockedUntil) {
+			lockedUntil = until.Time
+		}
+	}
+
+	if time.Now().UTC().After(lockedUntil) {
+		return time.Time{}, nil
+	}
+	return lockedUntil, nil
+}
+
+// counts a failed login against the email and the ip and locks them once their limit is reached
+func (apiConfig *ApiConfig) recordLoginFailure(ctx context.Context, email string, ip string) error {
+	config := apiConfig.LoginThrottle.withDefaults()
+	now := time.Now().UTC()
+
+	limits := map[string]struct {
+		identifier  string
+		maxFailures int
+	}{
+		loginScopeAccount: {identifier: email, maxFailures: config.MaxAccountFailures},
+		loginScopeIP:      {identifier: ip, maxFailures: config.MaxIPFailures},
+	}
+	for scope, limit := range limits {
+		failures, err := apiConfig.DB.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Scope:        scope,
+			Identifier:   limit.identifier,
+			LastFailedAt: now,
+			WindowStart:  now.Add(-config.FailureWindow),
+		})
+		if err != nil {
+			return err
+		}
+
+		if lockout := config.lockoutFor(int(failures), limit.maxFailures); lockout > 0 {
+			if err = apiConfig.DB.LockLogin(ctx, database.LockLoginParams{
+				LockedUntil: sql.NullTime{Time: now.Add(lockout), Valid: true},
+				Scope:       scope,
+				Identifier:  limit.identifier,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// responds with the time after which the client can try logging in again
+func respondWithLoginLocked(w http.ResponseWriter, lockedUntil time.Time) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(time.Until(lockedUntil).Seconds())+1))
+	utility.RespondWithError(w, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+}
+
+func (apiConfig *ApiConfig) HandleUnlockAccount(w http.ResponseWriter, r *http.Request) {
+	type unlockAccountRequest struct {
+		UserID uuid.UUID `json:"user_id"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := unlockAccountRequest{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if params.UserID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	// failed logins are counted against the email of the account
+	user, err := apiConfig.DB.GetUserByID(r.Context(), params.UserID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	unlocked, err := apiConfig.DB.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{
+		Scope:      loginScopeAccount,
+		Identifier: user.Email,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if unlocked == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "account has no failed logins")
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/contentfilter"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/permission"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+)
+
+// moderation states of a comment, only approved and hidden comments are shown in threads
+// and hidden ones are rendered as removed
+const (
+	commentStatusPending  = "pending"
+	commentStatusApproved = "approved"
+	commentStatusRejected = "rejected"
+	commentStatusHidden   = "hidden"
+)
+
+// maximum number of comments moderated in one request
+const maxModerationBatch = 100
+
+// initialCommentStatus approves the comments of moderators and of users having enough
+// approved comments right away, every other comment waits in the moderation queue
+func (apiConfig *ApiConfig) initialCommentStatus(ctx context.Context, IDAndRole *IDAndRole) (string, error) {
+	grantedPermissions, err := apiConfig.DB.GetPermissionsByRoleName(ctx, IDAndRole.Role)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(grantedPermissions, permission.CommentModerate) {
+		return commentStatusApproved, nil
+	}
+
+	approvedComments, err := apiConfig.DB.CountApprovedCommentsByUser(ctx, IDAndRole.ID)
+	if err != nil {
+		return "", err
+	}
+	if approvedComments >= int64(apiConfig.Comments.withDefaults().AutoApproveAfter) {
+		return commentStatusApproved, nil
+	}
+
+	return commentStatusPending, nil
+}
+
+// respondWithRejectedComment tells the user every rule the comment broke so that it can be fixed
+func respondWithRejectedComment(w http.ResponseWriter, reasons []contentfilter.Reason) {
+	type Response struct {
+		Error   string                 `json:"error"`
+		Reasons []contentfilter.Reason `json:"reasons"`
+	}
+
+	utility.RespondWithJson(w, http.StatusUnprocessableEntity, Response{
+		Error:   "comment rejected by the content filter",
+		Reasons: reasons,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleGetModerationQueue(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Comments   []database.GetModerationQueueRow `json:"comments"`
+		NextCursor string                           `json:"next_cursor,omitempty"`
+	}
+
+	// pending comments are paged from the oldest to the newest
+	page, err := pagination.FromRequest[time.Time](r)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pendingComments, err := apiConfig.DB.GetModerationQueue(r.Context(), database.GetModerationQueueParams{
+		AfterCreatedAt: afterCreatedAt(page),
+		AfterID:        page.AfterID(),
+		PageLimit:      page.FetchLimit(),
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	pendingComments, nextCursor := pagination.Trim(page, pendingComments, func(comment database.GetModerationQueueRow) (time.Time, uuid.UUID) {
+		return comment.CreatedAt, comment.ID
+	})
+	if pendingComments == nil {
+		pendingComments = make([]database.GetModerationQueueRow, 0)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Comments:   pendingComments,
+		NextCursor: nextCursor,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleModerateComments(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Request struct {
+		IDs    []uuid.UUID `json:"ids"`
+		Status string      `json:"status"`
+	}
+
+	type Response struct {
+		Moderated []uuid.UUID `json:"moderated"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if len(params.IDs) == 0 || len(params.IDs) > maxModerationBatch {
+		utility.RespondWithError(w, http.StatusBadRequest, "between 1 and 100 comment ids are required")
+		return
+	}
+
+	// pending is not a moderation decision
+	if !slices.Contains([]string{commentStatusApproved, commentStatusRejected, commentStatusHidden}, params.Status) {
+		utility.RespondWithError(w, http.StatusBadRequest, "status must be approved, rejected or hidden")
+		return
+	}
+
+	moderated, err := apiConfig.DB.SetCommentsStatus(r.Context(), database.SetCommentsStatusParams{
+		Status:      params.Status,
+		ModeratedBy: uuid.NullUUID{UUID: IDAndRole.ID, Valid: true},
+		Ids:         params.IDs,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if moderated == nil {
+		moderated = make([]uuid.UUID, 0)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Moderated: moderated,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleRemoveCommentAsModerator(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Request struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if params.ID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid comment id")
+		return
+	}
+
+	// hiding the comment keeps its replies readable while it is rendered as removed
+	moderated, err := apiConfig.DB.SetCommentsStatus(r.Context(), database.SetCommentsStatusParams{
+		Status:      commentStatusHidden,
+		ModeratedBy: uuid.NullUUID{UUID: IDAndRole.ID, Valid: true},
+		Ids:         []uuid.UUID{params.ID},
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(moderated) == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "comment not found")
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
+)
+
+// creation time of the last row of the previous page for the keyset queries
+func afterCreatedAt(page pagination.Page[time.Time]) sql.NullTime {
+	createdAt, ok := page.AfterKey()
+	return sql.NullTime{Time: createdAt, Valid: ok}
+}
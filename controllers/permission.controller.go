@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/permission"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+)
+
+type rolePermissionRequest struct {
+	RoleID     uuid.UUID `json:"role_id"`
+	Permission string    `json:"permission"`
+}
+
+// decodes and validates the role and permission of a grant or revoke request
+func (apiConfig *ApiConfig) decodeRolePermissionRequest(w http.ResponseWriter, r *http.Request) (rolePermissionRequest, bool) {
+	decoder := json.NewDecoder(r.Body)
+	params := rolePermissionRequest{}
+	if err := decoder.Decode(&params); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return params, false
+	}
+
+	if params.RoleID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid role id")
+		return params, false
+	}
+	if !permission.IsValid(params.Permission) {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid permission")
+		return params, false
+	}
+
+	// checking if the role exist
+	if _, err := apiConfig.DB.GetRoleById(r.Context(), params.RoleID); err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, "role not found")
+		return params, false
+	}
+
+	return params, true
+}
+
+func (apiConfig *ApiConfig) HandleGrantPermission(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	params, ok := apiConfig.decodeRolePermissionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// granting the permission, granting it again does nothing
+	if err := apiConfig.DB.GrantPermission(r.Context(), database.GrantPermissionParams{
+		RoleID:     params.RoleID,
+		Permission: params.Permission,
+	}); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err := apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionGrantPermission, params.RoleID, uuid.Nil, "granted permission "+params.Permission); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+func (apiConfig *ApiConfig) HandleRevokePermission(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	params, ok := apiConfig.decodeRolePermissionRequest(w, r)
+	if !ok {
+		return
+	}
+
+	revoked, err := apiConfig.DB.RevokePermission(r.Context(), database.RevokePermissionParams{
+		RoleID:     params.RoleID,
+		Permission: params.Permission,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if revoked == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "permission not granted to this role")
+		return
+	}
+	if err = apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionRevokePermission, params.RoleID, uuid.Nil, "revoked permission "+params.Permission); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+func (apiConfig *ApiConfig) HandleGetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	type Permission struct {
+		Permission string    `json:"permission"`
+		CreatedAt  time.Time `json:"created_at"`
+	}
+
+	type Response struct {
+		Permissions []Permission `json:"permissions"`
+		Available   []string     `json:"available"`
+	}
+
+	// extracting role id from query parameters
+	roleID, err := uuid.Parse(r.URL.Query().Get("role_id"))
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid role id")
+		return
+	}
+
+	rolePermissions, err := apiConfig.DB.GetPermissionsByRoleID(r.Context(), roleID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	permissions := make([]Permission, 0, len(rolePermissions))
+	for _, rolePermission := range rolePermissions {
+		permissions = append(permissions, Permission{
+			Permission: rolePermission.Permission,
+			CreatedAt:  rolePermission.CreatedAt,
+		})
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Permissions: permissions,
+		Available:   permission.All(),
+	})
+}
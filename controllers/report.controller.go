@@ -0,0 +1,467 @@
+package controllers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+)
+
+// content which can be reported
+const (
+	reportTargetBlog    = "blog"
+	reportTargetComment = "comment"
+)
+
+// reasons a reporter can pick from
+var reportReasons = []string{"spam", "harassment", "hate_speech", "misinformation", "off_topic", "other"}
+
+// actions a moderator resolves the open reports of a target with
+const (
+	reportActionDismiss = "dismiss"
+	reportActionHide    = "hide"
+	reportActionWarn    = "warn"
+)
+
+// states of a report once it is resolved
+const (
+	reportStatusDismissed = "dismissed"
+	reportStatusActioned  = "actioned"
+)
+
+// reporting options, zero values are replaced by the defaults
+type ReportConfig struct {
+	// number of open reports after which the reported content is hidden until a moderator looks at it
+	AutoHideThreshold int
+}
+
+func (config ReportConfig) withDefaults() ReportConfig {
+	if config.AutoHideThreshold <= 0 {
+		config.AutoHideThreshold = 5
+	}
+
+	return config
+}
+
+// reportTargetAuthor returns the user who wrote the reported blog or comment
+func (apiConfig *ApiConfig) reportTargetAuthor(ctx context.Context, targetType string, targetID uuid.UUID) (uuid.UUID, error) {
+	if targetType == reportTargetBlog {
+		return apiConfig.DB.GetBlogAuthorByID(ctx, targetID)
+	}
+
+	return apiConfig.DB.GetCommentAuthorByID(ctx, targetID)
+}
+
+// commentAfterAutoHide hides a comment once enough users reported it, only published comments
+// are hidden as pending and rejected ones are not shown anyway and a moderator decision is kept
+func commentAfterAutoHide(state database.GetCommentHideStateRow) (database.GetCommentHideStateRow, bool) {
+	if state.Status != commentStatusApproved {
+		return state, false
+	}
+
+	return database.GetCommentHideStateRow{
+		Status:           commentStatusHidden,
+		StatusBeforeHide: sql.NullString{String: state.Status, Valid: true},
+	}, true
+}
+
+// commentAfterDismiss gives a comment hidden by reports its previous status back,
+// comments hidden by a moderator have no previous status and stay hidden
+func commentAfterDismiss(state database.GetCommentHideStateRow) (database.GetCommentHideStateRow, bool) {
+	if state.Status != commentStatusHidden || !state.StatusBeforeHide.Valid {
+		return state, false
+	}
+
+	return database.GetCommentHideStateRow{
+		Status: state.StatusBeforeHide.String,
+	}, true
+}
+
+// blogAfterAutoHide hides a visible blog once enough users reported it,
+// a blog already hidden by a moderator is left as it is
+func blogAfterAutoHide(state database.GetBlogHideStateRow) (database.GetBlogHideStateRow, bool) {
+	if state.Hidden {
+		return state, false
+	}
+
+	return database.GetBlogHideStateRow{
+		Hidden:     true,
+		AutoHidden: true,
+	}, true
+}
+
+// blogAfterDismiss shows a blog again only when it was hidden by reports
+func blogAfterDismiss(state database.GetBlogHideStateRow) (database.GetBlogHideStateRow, bool) {
+	if !state.Hidden || !state.AutoHidden {
+		return state, false
+	}
+
+	return database.GetBlogHideStateRow{}, true
+}
+
+// blogAfterModeratorHide hides a blog whatever hid it before, so that it stays hidden when
+// the reports against it are dismissed
+func blogAfterModeratorHide(database.GetBlogHideStateRow) (database.GetBlogHideStateRow, bool) {
+	return database.GetBlogHideStateRow{Hidden: true}, true
+}
+
+// transitionReportTarget moves the reported content to the state computed by transition, the
+// update only applies if the content was not changed since its state was read. A nil moderator
+// means the change is made automatically
+func (apiConfig *ApiConfig) transitionReportTarget(
+	ctx context.Context,
+	targetType string,
+	targetID uuid.UUID,
+	moderatorID uuid.NullUUID,
+	blogTransition func(database.GetBlogHideStateRow) (database.GetBlogHideStateRow, bool),
+	commentTransition func(database.GetCommentHideStateRow) (database.GetCommentHideStateRow, bool),
+) error {
+	if targetType == reportTargetBlog {
+		current, err := apiConfig.DB.GetBlogHideState(ctx, targetID)
+		if err != nil {
+			return err
+		}
+		next, changed := blogTransition(current)
+		if !changed {
+			return nil
+		}
+
+		hiddenBy := uuid.NullUUID{}
+		if next.Hidden {
+			hiddenBy = moderatorID
+		}
+		if _, err = apiConfig.DB.UpdateBlogHideState(ctx, database.UpdateBlogHideStateParams{
+			Hidden:            next.Hidden,
+			AutoHidden:        next.AutoHidden,
+			HiddenBy:          hiddenBy,
+			ID:                targetID,
+			CurrentHidden:     current.Hidden,
+			CurrentAutoHidden: current.AutoHidden,
+		}); err != nil {
+			return err
+		}
+
+		apiConfig.invalidateSearchSuggestions()
+		return nil
+	}
+
+	// removed comments are not shown anyway so there is nothing to hide or restore
+	current, err := apiConfig.DB.GetCommentHideState(ctx, targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	next, changed := commentTransition(current)
+	if !changed {
+		return nil
+	}
+
+	_, err = apiConfig.DB.UpdateCommentHideState(ctx, database.UpdateCommentHideStateParams{
+		Status:           next.Status,
+		StatusBeforeHide: next.StatusBeforeHide,
+		ID:               targetID,
+		CurrentStatus:    current.Status,
+	})
+	return err
+}
+
+// autoHideReportTarget hides the reported content once enough users reported it
+func (apiConfig *ApiConfig) autoHideReportTarget(ctx context.Context, targetType string, targetID uuid.UUID) error {
+	return apiConfig.transitionReportTarget(ctx, targetType, targetID, uuid.NullUUID{}, blogAfterAutoHide, commentAfterAutoHide)
+}
+
+// restoreReportTarget shows the content again after its reports were dismissed,
+// content is only restored when it was hidden by reports and not by a moderator
+func (apiConfig *ApiConfig) restoreReportTarget(ctx context.Context, targetType string, targetID uuid.UUID) error {
+	return apiConfig.transitionReportTarget(ctx, targetType, targetID, uuid.NullUUID{}, blogAfterDismiss, commentAfterDismiss)
+}
+
+// hideReportTarget hides the reported content on behalf of a moderator
+func (apiConfig *ApiConfig) hideReportTarget(ctx context.Context, targetType string, targetID uuid.UUID, moderatorID uuid.UUID) error {
+	if targetType == reportTargetBlog {
+		return apiConfig.transitionReportTarget(ctx, targetType, targetID, uuid.NullUUID{UUID: moderatorID, Valid: true}, blogAfterModeratorHide, nil)
+	}
+
+	_, err := apiConfig.DB.SetCommentsStatus(ctx, database.SetCommentsStatusParams{
+		Status:      commentStatusHidden,
+		ModeratedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+		Ids:         []uuid.UUID{targetID},
+	})
+	return err
+}
+
+// user
+func (apiConfig *ApiConfig) HandleCreateReport(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Request struct {
+		TargetType string    `json:"targetType"`
+		TargetID   uuid.UUID `json:"targetID"`
+		Reason     string    `json:"reason"`
+		Details    string    `json:"details"`
+	}
+
+	type Response struct {
+		ID        uuid.UUID `json:"id"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if params.TargetType != reportTargetBlog && params.TargetType != reportTargetComment {
+		utility.RespondWithError(w, http.StatusBadRequest, "target type must be blog or comment")
+		return
+	}
+	if params.TargetID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid target id")
+		return
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid report reason")
+		return
+	}
+	if err = apiConfig.DataValidator.Var(params.Details, "max=500"); err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "details are too large")
+		return
+	}
+
+	// checking that the reported content exists and was not written by the reporter
+	authorID, err := apiConfig.reportTargetAuthor(r.Context(), params.TargetType, params.TargetID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, params.TargetType+" not found")
+		return
+	}
+	if authorID == IDAndRole.ID {
+		utility.RespondWithError(w, http.StatusBadRequest, "can not report your own "+params.TargetType)
+		return
+	}
+
+	// every user can report the same content only once
+	newReport, err := apiConfig.DB.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID: IDAndRole.ID,
+		TargetType: params.TargetType,
+		TargetID:   params.TargetID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		utility.RespondWithError(w, http.StatusConflict, params.TargetType+" already reported")
+		return
+	}
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// hiding the content once enough users reported it
+	openReports, err := apiConfig.DB.CountOpenReports(r.Context(), database.CountOpenReportsParams{
+		TargetType: params.TargetType,
+		TargetID:   params.TargetID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if openReports >= int64(apiConfig.Reports.withDefaults().AutoHideThreshold) {
+		if err = apiConfig.autoHideReportTarget(r.Context(), params.TargetType, params.TargetID); err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	utility.RespondWithJson(w, http.StatusCreated, Response{
+		ID:        newReport.ID,
+		CreatedAt: newReport.CreatedAt,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleGetReportedTargets(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Targets    []database.GetReportedTargetsRow `json:"targets"`
+		NextCursor string                           `json:"next_cursor,omitempty"`
+	}
+
+	// reported content is paged from the most to the least reported
+	page, err := pagination.FromRequest[int64](r)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	afterOpenReports, hasPrevious := page.AfterKey()
+
+	targets, err := apiConfig.DB.GetReportedTargets(r.Context(), database.GetReportedTargetsParams{
+		AfterOpenReports: sql.NullInt64{Int64: afterOpenReports, Valid: hasPrevious},
+		AfterID:          page.AfterID(),
+		PageLimit:        page.FetchLimit(),
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	targets, nextCursor := pagination.Trim(page, targets, func(target database.GetReportedTargetsRow) (int64, uuid.UUID) {
+		return target.OpenReports, target.TargetID
+	})
+	if targets == nil {
+		targets = make([]database.GetReportedTargetsRow, 0)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Targets:    targets,
+		NextCursor: nextCursor,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleGetReportsByTarget(w http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Reports []database.GetOpenReportsByTargetRow `json:"reports"`
+	}
+
+	// extracting the reported content from the query
+	targetType := r.URL.Query().Get("target_type")
+	if targetType != reportTargetBlog && targetType != reportTargetComment {
+		utility.RespondWithError(w, http.StatusBadRequest, "target type must be blog or comment")
+		return
+	}
+	targetID, err := uuid.Parse(r.URL.Query().Get("target_id"))
+	if err != nil || targetID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid target id")
+		return
+	}
+
+	reports, err := apiConfig.DB.GetOpenReportsByTarget(r.Context(), database.GetOpenReportsByTargetParams{
+		TargetType: targetType,
+		TargetID:   targetID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if reports == nil {
+		reports = make([]database.GetOpenReportsByTargetRow, 0)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Reports: reports,
+	})
+}
+
+// admin
+func (apiConfig *ApiConfig) HandleResolveReports(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Request struct {
+		TargetType string    `json:"targetType"`
+		TargetID   uuid.UUID `json:"targetID"`
+		Action     string    `json:"action"`
+		Note       string    `json:"note"`
+	}
+
+	type Response struct {
+		ResolvedReports int64 `json:"resolvedReports"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if params.TargetType != reportTargetBlog && params.TargetType != reportTargetComment {
+		utility.RespondWithError(w, http.StatusBadRequest, "target type must be blog or comment")
+		return
+	}
+	if params.TargetID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid target id")
+		return
+	}
+	if params.Action == reportActionWarn {
+		if err = apiConfig.DataValidator.Var(params.Note, "required,max=500"); err != nil {
+			utility.RespondWithError(w, http.StatusBadRequest, "a note of at most 500 characters is required for a warning")
+			return
+		}
+	}
+
+	// checking that there is something to resolve
+	openReports, err := apiConfig.DB.CountOpenReports(r.Context(), database.CountOpenReportsParams{
+		TargetType: params.TargetType,
+		TargetID:   params.TargetID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if openReports == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "no open reports for this "+params.TargetType)
+		return
+	}
+
+	// applying the action before closing the reports so that a failure leaves them open
+	moderatorID := uuid.NullUUID{UUID: IDAndRole.ID, Valid: true}
+	status := reportStatusActioned
+	switch params.Action {
+	case reportActionDismiss:
+		status = reportStatusDismissed
+		err = apiConfig.restoreReportTarget(r.Context(), params.TargetType, params.TargetID)
+	case reportActionHide:
+		err = apiConfig.hideReportTarget(r.Context(), params.TargetType, params.TargetID, IDAndRole.ID)
+	case reportActionWarn:
+		var authorID uuid.UUID
+		authorID, err = apiConfig.reportTargetAuthor(r.Context(), params.TargetType, params.TargetID)
+		if err == nil {
+			err = apiConfig.DB.CreateUserWarning(r.Context(), database.CreateUserWarningParams{
+				UserID:     authorID,
+				IssuedBy:   moderatorID,
+				TargetType: params.TargetType,
+				TargetID:   params.TargetID,
+				Note:       params.Note,
+			})
+		}
+	default:
+		utility.RespondWithError(w, http.StatusBadRequest, "action must be dismiss, hide or warn")
+		return
+	}
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resolvedReports, err := apiConfig.DB.ResolveReports(r.Context(), database.ResolveReportsParams{
+		Status:     status,
+		Resolution: sql.NullString{String: params.Action, Valid: true},
+		ResolvedBy: moderatorID,
+		TargetType: params.TargetType,
+		TargetID:   params.TargetID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		ResolvedReports: resolvedReports,
+	})
+}
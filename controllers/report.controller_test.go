@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+func TestCommentReportTransitions(t *testing.T) {
+	hiddenByReports := database.GetCommentHideStateRow{
+		Status:           commentStatusHidden,
+		StatusBeforeHide: sql.NullString{String: commentStatusApproved, Valid: true},
+	}
+
+	tests := []struct {
+		name            string
+		status          string
+		expectedHidden  bool
+		expectedRestore string
+	}{
+		{"approved", commentStatusApproved, true, commentStatusApproved},
+		{"pending", commentStatusPending, false, ""},
+		{"rejected", commentStatusRejected, false, ""},
+		{"hidden_by_moderator", commentStatusHidden, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := database.GetCommentHideStateRow{Status: tt.status}
+
+			hidden, changed := commentAfterAutoHide(current)
+			if changed != tt.expectedHidden {
+				t.Fatalf("auto hide changed %v, want %v", changed, tt.expectedHidden)
+			}
+			if !changed {
+				if hidden != current {
+					t.Fatalf("unchanged state %+v, want %+v", hidden, current)
+				}
+
+				// dismissing reports never touches a comment reports did not hide
+				if _, restored := commentAfterDismiss(current); restored {
+					t.Fatal("dismiss restored a comment not hidden by reports")
+				}
+				return
+			}
+			if hidden != hiddenByReports {
+				t.Fatalf("auto hidden state %+v, want %+v", hidden, hiddenByReports)
+			}
+
+			restored, changed := commentAfterDismiss(hidden)
+			if !changed || restored.Status != tt.expectedRestore || restored.StatusBeforeHide.Valid {
+				t.Fatalf("dismiss returned %+v, %v", restored, changed)
+			}
+		})
+	}
+
+	// hiding an auto hidden comment again keeps its previous status
+	if _, changed := commentAfterAutoHide(hiddenByReports); changed {
+		t.Fatal("auto hide changed an already hidden comment")
+	}
+}
+
+func TestBlogReportTransitions(t *testing.T) {
+	visible := database.GetBlogHideStateRow{}
+	hiddenByReports := database.GetBlogHideStateRow{Hidden: true, AutoHidden: true}
+	hiddenByModerator := database.GetBlogHideStateRow{Hidden: true}
+
+	tests := []struct {
+		name     string
+		apply    func(database.GetBlogHideStateRow) (database.GetBlogHideStateRow, bool)
+		current  database.GetBlogHideStateRow
+		expected database.GetBlogHideStateRow
+		changed  bool
+	}{
+		{"auto_hide_visible", blogAfterAutoHide, visible, hiddenByReports, true},
+		{"auto_hide_hidden_by_moderator", blogAfterAutoHide, hiddenByModerator, hiddenByModerator, false},
+		{"auto_hide_hidden_by_reports", blogAfterAutoHide, hiddenByReports, hiddenByReports, false},
+		{"dismiss_hidden_by_reports", blogAfterDismiss, hiddenByReports, visible, true},
+		{"dismiss_hidden_by_moderator", blogAfterDismiss, hiddenByModerator, hiddenByModerator, false},
+		{"dismiss_visible", blogAfterDismiss, visible, visible, false},
+		{"moderator_hide_hidden_by_reports", blogAfterModeratorHide, hiddenByReports, hiddenByModerator, true},
+		{"moderator_hide_visible", blogAfterModeratorHide, visible, hiddenByModerator, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, changed := tt.apply(tt.current)
+			if next != tt.expected || changed != tt.changed {
+				t.Errorf("got %+v, %v, want %+v, %v", next, changed, tt.expected, tt.changed)
+			}
+		})
+	}
+}
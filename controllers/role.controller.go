@@ -1,24 +1,42 @@
 package controllers
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+	"github.com/lib/pq"
+)
+
+const (
+	roleActionCreate           = "create"
+	roleActionUpdate           = "update"
+	roleActionRemove           = "remove"
+	roleActionAssign           = "assign"
+	roleActionGrantPermission  = "grant_permission"
+	roleActionRevokePermission = "revoke_permission"
+
+	// postgres error code returned when a row is still referenced by another table
+	foreignKeyViolation = "23503"
 )
 
 // response struct
 type roleResponse struct {
-	Role        string    `json:"role"`
-	AccessToken string    `json:"access_token"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (apiConfig *ApiConfig) HandleCreateRole(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleCreateRole(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	// request struct
 	type role struct {
 		Role string `json:"role"`
@@ -44,16 +62,22 @@ func (apiConfig *ApiConfig) HandleCreateRole(w http.ResponseWriter, r *http.Requ
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if err = apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionCreate, newRole.ID, uuid.Nil, "created role "+newRole.RoleName); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	utility.RespondWithJson(w, http.StatusCreated, roleResponse{
-		Role:        newRole.RoleName,
-		AccessToken: newAccessToken,
-		CreatedAt:   newRole.CreatedAt,
-		UpdatedAt:   newRole.UpdatedAt,
+		Role:      newRole.RoleName,
+		CreatedAt: newRole.CreatedAt,
+		UpdatedAt: newRole.UpdatedAt,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleRemoveRole(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveRole(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	// request struct
 	type roleRequest struct {
 		RoleID uuid.UUID `json:"role_id"`
@@ -73,17 +97,40 @@ func (apiConfig *ApiConfig) HandleRemoveRole(w http.ResponseWriter, r *http.Requ
 		utility.RespondWithError(w, http.StatusNotAcceptable, "invalid role id")
 		return
 	}
-	if err = apiConfig.DB.RemoveRole(r.Context(), params.RoleID); err != nil {
+
+	// built-in roles are relied upon by the application and cannot be removed
+	role, ok := apiConfig.getModifiableRole(w, r, params.RoleID)
+	if !ok {
+		return
+	}
+
+	removed, err := apiConfig.DB.RemoveRole(r.Context(), params.RoleID)
+	if err != nil {
+		// a role can only be removed once no user has it
+		var pqError *pq.Error
+		if errors.As(err, &pqError) && pqError.Code == foreignKeyViolation {
+			utility.RespondWithError(w, http.StatusConflict, "role is still assigned to users")
+			return
+		}
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if removed == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "role not found")
+		return
+	}
+	if err = apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionRemove, params.RoleID, uuid.Nil, "removed role "+role.RoleName); err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utility.RespondWithJson(w, http.StatusOK, EmptyResponse{
-		AccessToken: newAccessToken,
-	})
+	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
-func (apiConfig *ApiConfig) HandleUpdateRole(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateRole(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	// request struct
 	type updateRoleRequest struct {
 		Role   string    `json:"role"`
@@ -104,6 +151,13 @@ func (apiConfig *ApiConfig) HandleUpdateRole(w http.ResponseWriter, r *http.Requ
 		utility.RespondWithError(w, http.StatusBadRequest, "invalid role")
 		return
 	}
+
+	// built-in roles are looked up by name so they cannot be renamed
+	role, ok := apiConfig.getModifiableRole(w, r, params.RoleID)
+	if !ok {
+		return
+	}
+
 	roleUpdate, err := apiConfig.DB.UpdateRoleById(r.Context(), database.UpdateRoleByIdParams{
 		RoleName: params.Role,
 		ID:       params.RoleID,
@@ -112,20 +166,22 @@ func (apiConfig *ApiConfig) HandleUpdateRole(w http.ResponseWriter, r *http.Requ
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if err = apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionUpdate, params.RoleID, uuid.Nil, "renamed role "+role.RoleName+" to "+roleUpdate.RoleName); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
 	utility.RespondWithJson(w, http.StatusOK, roleResponse{
-		Role:        roleUpdate.RoleName,
-		AccessToken: newAccessToken,
-		CreatedAt:   roleUpdate.CreatedAt,
-		UpdatedAt:   roleUpdate.UpdatedAt,
+		Role:      roleUpdate.RoleName,
+		CreatedAt: roleUpdate.CreatedAt,
+		UpdatedAt: roleUpdate.UpdatedAt,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleGetAllRoles(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleGetAllRoles(w http.ResponseWriter, r *http.Request) {
 	// response struct
 	type AllRoles struct {
-		Roles       []database.GetAllRolesRow `json:"roles"`
-		AccessToken string                    `json:"access_token"`
+		Roles []database.GetAllRolesRow `json:"roles"`
 	}
 	allRoles, err := apiConfig.DB.GetAllRoles(r.Context())
 	if err != nil {
@@ -134,7 +190,147 @@ func (apiConfig *ApiConfig) HandleGetAllRoles(w http.ResponseWriter, r *http.Req
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, AllRoles{
-		Roles:       allRoles,
-		AccessToken: newAccessToken,
+		Roles: allRoles,
+	})
+}
+
+// records who changed which role, targetUserID is uuid.Nil when the change is not about a user
+func (apiConfig *ApiConfig) auditRoleChange(ctx context.Context, actor *IDAndRole, action string, roleID uuid.UUID, targetUserID uuid.UUID, details string) error {
+	return apiConfig.DB.CreateRoleAuditLog(ctx, database.CreateRoleAuditLogParams{
+		ActorID:      uuid.NullUUID{UUID: actor.ID, Valid: true},
+		Action:       action,
+		RoleID:       roleID,
+		TargetUserID: uuid.NullUUID{UUID: targetUserID, Valid: targetUserID != uuid.Nil},
+		Details:      details,
+	})
+}
+
+// fetches the role and responds with an error if it does not exist or is built-in
+func (apiConfig *ApiConfig) getModifiableRole(w http.ResponseWriter, r *http.Request, roleID uuid.UUID) (database.GetRoleByIdRow, bool) {
+	role, err := apiConfig.DB.GetRoleById(r.Context(), roleID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utility.RespondWithError(w, http.StatusNotFound, "role not found")
+			return role, false
+		}
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return role, false
+	}
+	if role.IsBuiltin {
+		utility.RespondWithError(w, http.StatusForbidden, "built-in roles cannot be modified")
+		return role, false
+	}
+
+	return role, true
+}
+
+func (apiConfig *ApiConfig) HandleAssignRole(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	// request struct
+	type assignRoleRequest struct {
+		UserID uuid.UUID `json:"user_id"`
+		RoleID uuid.UUID `json:"role_id"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := assignRoleRequest{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if params.UserID == uuid.Nil || params.RoleID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid user or role id")
+		return
+	}
+
+	// checking if the role exist
+	role, err := apiConfig.DB.GetRoleById(r.Context(), params.RoleID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, "role not found")
+		return
+	}
+
+	// the last admin keeps the role so that roles can still be managed
+	if role.RoleName != "admin" {
+		isLastAdmin, err := apiConfig.DB.IsLastAdmin(r.Context(), params.UserID)
+		if err != nil {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if isLastAdmin {
+			utility.RespondWithError(w, http.StatusConflict, "cannot remove the role of the last admin")
+			return
+		}
+	}
+
+	// assigning the role, the middleware reads the role of the user from the
+	// session on every request so the change applies right away
+	assigned, err := apiConfig.DB.AssignUserRole(r.Context(), database.AssignUserRoleParams{
+		RoleID: params.RoleID,
+		ID:     params.UserID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if assigned == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "user not found")
+		return
+	}
+	if err = apiConfig.auditRoleChange(r.Context(), IDAndRole, roleActionAssign, params.RoleID, params.UserID, "assigned role "+role.RoleName); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+func (apiConfig *ApiConfig) HandleGetRoleAuditLog(w http.ResponseWriter, r *http.Request) {
+	// response struct
+	type AuditEntry struct {
+		ID           uuid.UUID  `json:"id"`
+		ActorID      *uuid.UUID `json:"actor_id"`
+		Action       string     `json:"action"`
+		RoleID       uuid.UUID  `json:"role_id"`
+		TargetUserID *uuid.UUID `json:"target_user_id,omitempty"`
+		Details      string     `json:"details"`
+		CreatedAt    time.Time  `json:"created_at"`
+	}
+
+	type Response struct {
+		Entries []AuditEntry `json:"entries"`
+	}
+
+	// fetching the latest role changes
+	auditLog, err := apiConfig.DB.GetRoleAuditLog(r.Context(), 100)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	entries := make([]AuditEntry, 0, len(auditLog))
+	for _, entry := range auditLog {
+		auditEntry := AuditEntry{
+			ID:        entry.ID,
+			Action:    entry.Action,
+			RoleID:    entry.RoleID,
+			Details:   entry.Details,
+			CreatedAt: entry.CreatedAt,
+		}
+		if entry.ActorID.Valid {
+			auditEntry.ActorID = &entry.ActorID.UUID
+		}
+		if entry.TargetUserID.Valid {
+			auditEntry.TargetUserID = &entry.TargetUserID.UUID
+		}
+		entries = append(entries, auditEntry)
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Entries: entries,
 	})
 }
@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
+	"github.com/harshvardha/artOfSoftwareEngineering/utility"
+)
+
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+type refreshTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// only the hash of a refresh token is stored so a leaked table cannot be used to login
+func hashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(hash[:])
+}
+
+// issues a new refresh token belonging to the session
+func (apiConfig *ApiConfig) issueRefreshToken(ctx context.Context, sessionID uuid.UUID, userID uuid.UUID, expiresAt time.Time) (string, error) {
+	refreshToken, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	if err = apiConfig.DB.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: hashRefreshToken(refreshToken),
+		SessionID: sessionID,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+// creates a new session for the device the user logged in from along with its first refresh token
+func (apiConfig *ApiConfig) createSession(ctx context.Context, userID uuid.UUID, deviceName string, userAgent string) (uuid.UUID, string, error) {
+	expiresAt := time.Now().UTC().Add(refreshTokenLifetime)
+	sessionID, err := apiConfig.DB.CreateSession(ctx, database.CreateSessionParams{
+		UserID:     userID,
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		ExpiresAt:  expiresAt,
+	})
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	refreshToken, err := apiConfig.issueRefreshToken(ctx, sessionID, userID, expiresAt)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	return sessionID, refreshToken, nil
+}
+
+// revokes every session of the user, used when the credentials of the user change
+func (apiConfig *ApiConfig) revokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	if err := apiConfig.DB.RevokeAllSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	return apiConfig.DB.RemoveRefreshToken(ctx, userID)
+}
+
+func (apiConfig *ApiConfig) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	type refreshTokenRequest struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := refreshTokenRequest{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if params.RefreshToken == "" {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid refresh token")
+		return
+	}
+
+	// marking the refresh token as used so that it can be used only once
+	tokenHash := hashRefreshToken(params.RefreshToken)
+	usedToken, err := apiConfig.DB.UseRefreshToken(r.Context(), tokenHash)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// if the token exist then it was already used, which means it was stolen
+		// so the whole session the token belongs to is revoked
+		sessionID, err := apiConfig.DB.GetRefreshTokenSession(r.Context(), tokenHash)
+		if err == nil {
+			if err = apiConfig.DB.RevokeSession(r.Context(), sessionID); err != nil {
+				utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			utility.RespondWithError(w, http.StatusUnauthorized, "refresh token reuse detected, please login again")
+			return
+		}
+
+		utility.RespondWithError(w, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	// checking if the refresh token is expired
+	if time.Now().UTC().After(usedToken.ExpiresAt) {
+		utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
+		return
+	}
+
+	// checking if the session is still active
+	session, err := apiConfig.DB.GetSessionByID(r.Context(), usedToken.SessionID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
+		return
+	}
+	if session.RevokedAt.Valid || time.Now().UTC().After(session.ExpiresAt) {
+		utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
+		return
+	}
+
+	// rotating the refresh token and extending the session
+	expiresAt := time.Now().UTC().Add(refreshTokenLifetime)
+	refreshToken, err := apiConfig.issueRefreshToken(r.Context(), usedToken.SessionID, usedToken.UserID, expiresAt)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err = apiConfig.DB.TouchSession(r.Context(), database.TouchSessionParams{
+		ExpiresAt: expiresAt,
+		ID:        usedToken.SessionID,
+	}); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// creating access token
+	accessToken, err := apiConfig.TokenManager.Issue(token.Claims{
+		UserID:    usedToken.UserID.String(),
+		Role:      session.RoleName,
+		SessionID: usedToken.SessionID.String(),
+	}, token.AccessTokenExpiry)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, refreshTokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+}
+
+func (apiConfig *ApiConfig) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	// revoking the session the access token belongs to
+	if _, err := apiConfig.DB.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		ID:     IDAndRole.SessionID,
+		UserID: IDAndRole.ID,
+	}); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+func (apiConfig *ApiConfig) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	// revoking every session of the user on every device
+	if err := apiConfig.revokeAllSessions(r.Context(), IDAndRole.ID); err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
+
+func (apiConfig *ApiConfig) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Session struct {
+		ID         uuid.UUID `json:"id"`
+		DeviceName string    `json:"deviceName"`
+		UserAgent  string    `json:"userAgent"`
+		Current    bool      `json:"current"`
+		LastUsedAt time.Time `json:"lastUsedAt"`
+		ExpiresAt  time.Time `json:"expiresAt"`
+		CreatedAt  time.Time `json:"createdAt"`
+	}
+
+	type Response struct {
+		Sessions []Session `json:"sessions"`
+	}
+
+	// fetching all the active sessions of the user
+	activeSessions, err := apiConfig.DB.GetActiveSessionsByUserID(r.Context(), database.GetActiveSessionsByUserIDParams{
+		UserID:    IDAndRole.ID,
+		ExpiresAt: time.Now().UTC(),
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sessions := make([]Session, 0, len(activeSessions))
+	for _, session := range activeSessions {
+		sessions = append(sessions, Session{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			UserAgent:  session.UserAgent,
+			Current:    session.ID == IDAndRole.SessionID,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			CreatedAt:  session.CreatedAt,
+		})
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, Response{
+		Sessions: sessions,
+	})
+}
+
+func (apiConfig *ApiConfig) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
+	type Request struct {
+		ID uuid.UUID `json:"id"`
+	}
+
+	// decoding request body
+	decoder := json.NewDecoder(r.Body)
+	params := Request{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if params.ID == uuid.Nil {
+		utility.RespondWithError(w, http.StatusBadRequest, "invalid session id")
+		return
+	}
+
+	// revoking the session only if it belongs to the user
+	revoked, err := apiConfig.DB.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		ID:     params.ID,
+		UserID: IDAndRole.ID,
+	})
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if revoked == 0 {
+		utility.RespondWithError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, nil)
+}
@@ -3,16 +3,24 @@ package controllers
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/cache"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/search"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
 )
 
-func (apiConfig *ApiConfig) HandleUpdateEmail(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateEmail(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type UpdateEmailRequest struct {
 		VerificationToken string `json:"verificationToken"`
 		OTP               string `json:"otp"`
@@ -28,18 +36,18 @@ func (apiConfig *ApiConfig) HandleUpdateEmail(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// checking if otp is valid and correct
-	if err = apiConfig.OtpCache.VerifyOTP(params.VerificationToken, params.OTP); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
 	// checking if the email is valid or not
 	if err = apiConfig.DataValidator.Var(params.NewEmail, "required,email"); err != nil {
 		utility.RespondWithError(w, http.StatusNotAcceptable, err.Error())
 		return
 	}
 
+	// checking if otp is valid, correct and was sent to the new email for an email change
+	if err = apiConfig.OtpCache.VerifyOTP(r.Context(), params.VerificationToken, params.OTP, params.NewEmail, cache.PurposeEmailChange); err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
+		return
+	}
+
 	// updating new email
 	if err = apiConfig.DB.UpdateEmail(r.Context(), database.UpdateEmailParams{
 		Email: params.NewEmail,
@@ -49,8 +57,8 @@ func (apiConfig *ApiConfig) HandleUpdateEmail(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// removing existing refresh token
-	if err = apiConfig.DB.RemoveRefreshToken(r.Context(), IDAndRole.ID); err != nil {
+	// revoking all the existing sessions
+	if err = apiConfig.revokeAllSessions(r.Context(), IDAndRole.ID); err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -58,7 +66,10 @@ func (apiConfig *ApiConfig) HandleUpdateEmail(w http.ResponseWriter, r *http.Req
 	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
-func (apiConfig *ApiConfig) HandleUpdatePassword(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdatePassword(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type UpdatePasswordRequest struct {
 		VerificationToken string `json:"verificationToken"`
 		OTP               string `json:"otp"`
@@ -74,9 +85,14 @@ func (apiConfig *ApiConfig) HandleUpdatePassword(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// checking if the otp is valid or not
-	if err = apiConfig.OtpCache.VerifyOTP(params.VerificationToken, params.OTP); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+	// checking if the otp is valid and was sent to this user for a password change
+	user, err := apiConfig.DB.GetUserByID(r.Context(), IDAndRole.ID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err = apiConfig.OtpCache.VerifyOTP(r.Context(), params.VerificationToken, params.OTP, user.Email, cache.PurposePasswordChange); err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
@@ -102,8 +118,8 @@ func (apiConfig *ApiConfig) HandleUpdatePassword(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// removing the existing refresh token
-	if err = apiConfig.DB.RemoveRefreshToken(r.Context(), IDAndRole.ID); err != nil {
+	// revoking all the existing sessions
+	if err = apiConfig.revokeAllSessions(r.Context(), IDAndRole.ID); err != nil {
 		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -111,7 +127,10 @@ func (apiConfig *ApiConfig) HandleUpdatePassword(w http.ResponseWriter, r *http.
 	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
-func (apiConfig *ApiConfig) HandleUpdateOtherDetails(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUpdateOtherDetails(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type UpdateUsernameOrProfilePic struct {
 		Username      string `json:"username,omitempty"`
 		ProfilePicURL string `json:"profilePicUrl,omitempty"`
@@ -154,18 +173,19 @@ func (apiConfig *ApiConfig) HandleUpdateOtherDetails(w http.ResponseWriter, r *h
 
 	type UpdatedUser struct {
 		UsernameAndProfilePic UpdateUsernameOrProfilePic `json:"updatedUsernameAndProfilePic"`
-		AccessToken           string                     `json:"accessToken"`
 	}
 	utility.RespondWithJson(w, http.StatusOK, UpdatedUser{
 		UsernameAndProfilePic: UpdateUsernameOrProfilePic{
 			Username:      updatedUserInformation.Username,
 			ProfilePicURL: updatedUserInformation.ProfilePicUrl,
 		},
-		AccessToken: newAccessToken,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleRemoveUserAccount(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleRemoveUserAccount(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	type RemoveUserAccountRequest struct {
 		VerificationToken string `json:"verificationToken"`
 		OTP               string `json:"otp"`
@@ -180,9 +200,14 @@ func (apiConfig *ApiConfig) HandleRemoveUserAccount(w http.ResponseWriter, r *ht
 		return
 	}
 
-	// verifying if the otp is correct or valid
-	if err = apiConfig.OtpCache.VerifyOTP(params.VerificationToken, params.OTP); err != nil {
-		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+	// verifying if the otp is correct, valid and was sent to this user for deleting the account
+	user, err := apiConfig.DB.GetUserByID(r.Context(), IDAndRole.ID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err = apiConfig.OtpCache.VerifyOTP(r.Context(), params.VerificationToken, params.OTP, user.Email, cache.PurposeAccountDeletion); err != nil {
+		utility.RespondWithError(w, otpErrorStatus(err), err.Error())
 		return
 	}
 
@@ -195,7 +220,10 @@ func (apiConfig *ApiConfig) HandleRemoveUserAccount(w http.ResponseWriter, r *ht
 	utility.RespondWithJson(w, http.StatusOK, nil)
 }
 
-func (apiConfig *ApiConfig) HandleGetUserByID(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleGetUserByID(w http.ResponseWriter, r *http.Request) {
+	// extracting the authenticated user from request context
+	IDAndRole := IDAndRoleFromContext(r.Context())
+
 	user, err := apiConfig.DB.GetUserByID(r.Context(), IDAndRole.ID)
 	if err != nil {
 		utility.RespondWithError(w, http.StatusNotFound, err.Error())
@@ -206,7 +234,6 @@ func (apiConfig *ApiConfig) HandleGetUserByID(w http.ResponseWriter, r *http.Req
 		Email         string    `json:"email"`
 		Username      string    `json:"username"`
 		ProfilePicURL string    `json:"profilePicUrl"`
-		AccessToken   string    `json:"accessToken"`
 		CreatedAt     time.Time `json:"created_at"`
 		UpdatedAt     time.Time `json:"updated_at"`
 	}
@@ -215,50 +242,97 @@ func (apiConfig *ApiConfig) HandleGetUserByID(w http.ResponseWriter, r *http.Req
 		Email:         user.Email,
 		Username:      user.Username,
 		ProfilePicURL: user.ProfilePicUrl,
-		AccessToken:   newAccessToken,
 		CreatedAt:     user.CreatedAt,
 		UpdatedAt:     user.UpdatedAt,
 	})
 }
 
-func (apiConfig *ApiConfig) HandleUserSearch(w http.ResponseWriter, r *http.Request, IDAndRole *IDAndRole, newAccessToken string) {
+func (apiConfig *ApiConfig) HandleUserSearch(w http.ResponseWriter, r *http.Request) {
 	// extracting user search query
-	searchQuery := r.URL.Query().Get("search")
+	searchQuery := strings.TrimSpace(r.URL.Query().Get("search"))
 	if searchQuery == "" {
 		utility.RespondWithError(w, http.StatusBadRequest, "empty search query")
 		return
 	}
 
-	// tokenizing the search query
-	searchTokens := strings.Split(searchQuery, " ")
-	if len(searchTokens) == 0 {
-		utility.RespondWithError(w, http.StatusBadRequest, "empty search query")
+	// blogs and books are paged by their own cursors, a cursor given for only
+	// one of them pages that one alone
+	blogsPage, err := pagination.FromRequestParam[float32](r, "blogs_cursor")
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	booksPage, err := pagination.FromRequestParam[float32](r, "books_cursor")
+	if err != nil {
+		utility.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	searchedBlogs, searchedBooks := &blogsPage, &booksPage
+	if blogsPage.After != nil && booksPage.After == nil {
+		searchedBooks = nil
+	}
+	if booksPage.After != nil && blogsPage.After == nil {
+		searchedBlogs = nil
+	}
 
-	// removing duplicates
-	uniqueTokens := make(map[string]struct{})
-	for _, token := range strings.Split(searchQuery, " ") {
-		if _, ok := uniqueTokens[token]; !ok {
-			uniqueTokens[token] = struct{}{}
+	// extracting the facet values selected to refine the results
+	query := r.URL.Query()
+	filters := search.Filters{
+		Category: query.Get("category"),
+		Level:    query.Get("level"),
+	}
+	if filters.Category != "" {
+		filters.Category = cases.Title(language.English).String(filters.Category)
+	}
+	if value := query.Get("tags"); value != "" {
+		filters.Tags = strings.Split(value, ",")
+	}
+	if value := query.Get("year"); value != "" {
+		year, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || year <= 0 {
+			utility.RespondWithError(w, http.StatusBadRequest, "invalid year")
+			return
 		}
+		filters.Year = int32(year)
 	}
-	var tokens []string
-	for token := range uniqueTokens {
-		tokens = append(tokens, token)
+
+	// sending the query to search, it is parsed and ranked by the database
+	results, err := search.Search(searchQuery, filters, searchedBlogs, searchedBooks, r.Context(), apiConfig.DB)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	// sending uniqueTokens to search
-	blogs, books := search.Search(tokens, r.Context(), apiConfig.DB)
 	type Results struct {
-		Blogs       []*search.Blog `json:"blogs"`
-		Books       []*search.Book `json:"books"`
-		AccessToken string         `json:"accessToken"`
+		Blogs           []search.Blog `json:"blogs"`
+		Books           []search.Book `json:"books"`
+		BlogsNextCursor string        `json:"blogs_next_cursor,omitempty"`
+		BooksNextCursor string        `json:"books_next_cursor,omitempty"`
+		Facets          search.Facets `json:"facets"`
 	}
 
 	utility.RespondWithJson(w, http.StatusOK, Results{
-		Blogs:       blogs,
-		Books:       books,
-		AccessToken: newAccessToken,
+		Blogs:           results.Blogs,
+		Books:           results.Books,
+		BlogsNextCursor: results.BlogsNextCursor,
+		BooksNextCursor: results.BooksNextCursor,
+		Facets:          results.Facets,
 	})
 }
+
+func (apiConfig *ApiConfig) HandleSearchSuggest(w http.ResponseWriter, r *http.Request) {
+	// extracting the prefix typed so far
+	prefix := strings.TrimSpace(r.URL.Query().Get("q"))
+	if prefix == "" {
+		utility.RespondWithError(w, http.StatusBadRequest, "empty search query")
+		return
+	}
+
+	suggestions, err := apiConfig.SuggestCache.Suggest(r.Context(), apiConfig.DB, prefix)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utility.RespondWithJson(w, http.StatusOK, suggestions)
+}
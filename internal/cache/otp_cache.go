@@ -1,106 +1,233 @@
 package cache
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/smtp"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/mailer"
+)
+
+var (
+	ErrOTPNotFound      = errors.New("invalid verification token")
+	ErrOTPExpired       = errors.New("otp expired")
+	ErrOTPIncorrect     = errors.New("incorrect otp")
+	ErrOTPLocked        = errors.New("too many incorrect attempts, request a new otp")
+	ErrOTPQuotaExceeded = errors.New("too many otps requested for this email, try again later")
+	ErrOTPResendTooSoon = errors.New("otp resend not allowed yet")
+	ErrOTPMismatch      = errors.New("verification token was not issued for this email or action")
 )
 
-type emailConfig struct {
-	fromEmail   string
-	subject     string
-	body        string
-	smtpHost    string
-	smtpPort    string
-	appPassword string
+// time given to the mail server for delivering a password reset otp in the background
+const passwordResetMailTimeout = 30 * time.Second
+
+// otp cache options
+type Config struct {
+	// address the otp emails are sent from
+	FromEmail string
+
+	// interval at which expired entries are swept from the store, sweeping is disabled if <= 0
+	SweepInterval time.Duration
+
+	// number of incorrect otps after which a verification token is locked
+	MaxVerifyAttempts int
+
+	// maximum number of otps sent to one email within SendQuotaWindow
+	SendQuota       int
+	SendQuotaWindow time.Duration
 }
 
-// struct to store the otp cache data
-type otpCacheData struct {
-	otp      string
-	issuedAt time.Time
+// snapshot of the otp cache counters
+type Metrics struct {
+	ExpiredEvictions  int64 `json:"expired_evictions"`
+	CapacityEvictions int64 `json:"capacity_evictions"`
+	SweepRuns         int64 `json:"sweep_runs"`
+	SweepErrors       int64 `json:"sweep_errors"`
 }
 
 // otp cache struct
 type OtpCache struct {
-	cache              map[string]otpCacheData
-	lock               sync.Mutex
+	store              OTPStore
 	expiresAfter       time.Duration
 	resendAllowedAfter time.Duration
-	emailConfig        *emailConfig
+	mailer             mailer.Mailer
+	fromEmail          string
+	maxVerifyAttempts  int
+	sendQuota          int
+	sendQuotaWindow    time.Duration
+	expiredEvictions   atomic.Int64
+	sweepRuns          atomic.Int64
+	sweepErrors        atomic.Int64
+	done               chan struct{}
+	closeOnce          sync.Once
+	sweeperWaitGroup   sync.WaitGroup
+	mailWaitGroup      sync.WaitGroup
 }
 
-func NewOTPCache(fromEmail string, subject string, body string, smtpHost string, smtpPort string, appPassword string) *OtpCache {
-	return &OtpCache{
-		cache:              make(map[string]otpCacheData),
+func NewOTPCache(store OTPStore, mailer mailer.Mailer, config Config) *OtpCache {
+	otpCache := &OtpCache{
+		store:              store,
 		expiresAfter:       2 * time.Minute,
 		resendAllowedAfter: 4 * time.Minute,
-		emailConfig: &emailConfig{
-			fromEmail:   fromEmail,
-			subject:     "Registration OTP",
-			body:        body,
-			smtpHost:    smtpHost,
-			smtpPort:    smtpPort,
-			appPassword: appPassword,
-		},
+		mailer:             mailer,
+		fromEmail:          config.FromEmail,
+		maxVerifyAttempts:  config.MaxVerifyAttempts,
+		sendQuota:          config.SendQuota,
+		sendQuotaWindow:    config.SendQuotaWindow,
+		done:               make(chan struct{}),
 	}
+	if otpCache.maxVerifyAttempts <= 0 {
+		otpCache.maxVerifyAttempts = 5
+	}
+	if otpCache.sendQuota <= 0 {
+		otpCache.sendQuota = 5
+	}
+	if otpCache.sendQuotaWindow <= 0 {
+		otpCache.sendQuotaWindow = time.Hour
+	}
+
+	// starting the janitor which removes the abandoned otps
+	if config.SweepInterval > 0 {
+		otpCache.sweeperWaitGroup.Add(1)
+		go otpCache.sweep(config.SweepInterval)
+	}
+
+	return otpCache
 }
 
-func (otpCache *OtpCache) set(verificationToken string, otp string) (bool, error) {
-	otpCache.lock.Lock()
-	defer otpCache.lock.Unlock()
+func (otpCache *OtpCache) sweep(interval time.Duration) {
+	defer otpCache.sweeperWaitGroup.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-otpCache.done:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			otpCache.sweepOnce(ctx)
+			cancel()
+		}
+	}
+}
+
+// sweepOnce removes the otps which can neither be verified nor resent anymore
+// and the send records outside the quota window
+func (otpCache *OtpCache) sweepOnce(ctx context.Context) {
+	otpCache.sweepRuns.Add(1)
+
+	// an expired otp is kept for resending it, once the resend cooldown is over
+	// it can be resent for as long as a new otp would stay valid
+	retainFor := max(otpCache.expiresAfter, otpCache.resendAllowedAfter) + otpCache.expiresAfter
+	removed, err := otpCache.store.Expire(ctx, time.Now().Add(-retainFor))
+	if err != nil {
+		otpCache.sweepErrors.Add(1)
+		log.Println("Error sweeping expired otps: ", err)
+	} else {
+		otpCache.expiredEvictions.Add(removed)
+	}
+
+	if _, err = otpCache.store.ExpireSends(ctx, time.Now().Add(-otpCache.sendQuotaWindow)); err != nil {
+		otpCache.sweepErrors.Add(1)
+		log.Println("Error sweeping otp send records: ", err)
+	}
+}
+
+// Close stops the background sweeper and waits for it and the emails still being sent to finish
+func (otpCache *OtpCache) Close() {
+	otpCache.closeOnce.Do(func() {
+		close(otpCache.done)
+	})
+	otpCache.sweeperWaitGroup.Wait()
+	otpCache.mailWaitGroup.Wait()
+}
 
+func (otpCache *OtpCache) Metrics() Metrics {
+	metrics := Metrics{
+		ExpiredEvictions: otpCache.expiredEvictions.Load(),
+		SweepRuns:        otpCache.sweepRuns.Load(),
+		SweepErrors:      otpCache.sweepErrors.Load(),
+	}
+
+	// only stores with a capacity limit report capacity evictions
+	if boundedStore, ok := otpCache.store.(interface{ CapacityEvictions() int64 }); ok {
+		metrics.CapacityEvictions = boundedStore.CapacityEvictions()
+	}
+
+	return metrics
+}
+
+func (otpCache *OtpCache) set(ctx context.Context, verificationToken string, otp string, email string, purpose Purpose) (bool, error) {
 	// checking if all the info is provided or not
-	if verificationToken == "" || otp == "" {
+	if verificationToken == "" || otp == "" || email == "" || purpose == "" {
 		return false, errors.New("incomplete value to store in cache")
 	}
 
-	otpCache.cache[verificationToken] = otpCacheData{
-		otp:      otp,
-		issuedAt: time.Now(),
+	if err := otpCache.store.Set(ctx, verificationToken, OTPData{
+		OTP:      otp,
+		Email:    email,
+		Purpose:  purpose,
+		IssuedAt: time.Now(),
+	}); err != nil {
+		return false, err
 	}
 
 	return true, nil
 }
 
-func (otpCache *OtpCache) get(verificationToken string) (otpCacheData, error) {
-	otpCache.lock.Lock()
-	defer otpCache.lock.Unlock()
-
+func (otpCache *OtpCache) get(ctx context.Context, verificationToken string) (OTPData, error) {
 	// checking if the verificaiton token is empty
 	if len(verificationToken) == 0 {
-		return otpCacheData{}, errors.New("invalid verification token")
+		return OTPData{}, ErrOTPNotFound
 	}
 
-	data, exists := otpCache.cache[verificationToken]
-	if !exists {
-		return data, errors.New("invalid verification token: data not found")
+	data, err := otpCache.store.Get(ctx, verificationToken)
+	if errors.Is(err, errOTPDataNotFound) {
+		return OTPData{}, ErrOTPNotFound
 	}
 
-	return data, nil
+	return data, err
 }
 
-func (otpCache *OtpCache) delete(verificationToken string) (bool, error) {
-	otpCache.lock.Lock()
-	defer otpCache.lock.Unlock()
-
+func (otpCache *OtpCache) delete(ctx context.Context, verificationToken string) (bool, error) {
 	// checking if the verification token is valid
 	if len(verificationToken) == 0 {
-		return false, errors.New("invalid verification token: empty")
+		return false, ErrOTPNotFound
 	}
 
-	if _, exists := otpCache.cache[verificationToken]; exists {
-		delete(otpCache.cache, verificationToken)
-		return true, nil
-	} else {
-		return false, errors.New("invalid verification token: data not found")
+	if err := otpCache.store.Delete(ctx, verificationToken); err != nil {
+		if errors.Is(err, errOTPDataNotFound) {
+			return false, ErrOTPNotFound
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// checks and records an otp send against the per email quota
+func (otpCache *OtpCache) consumeSendQuota(ctx context.Context, email string) error {
+	now := time.Now()
+	sent, err := otpCache.store.CountSends(ctx, email, now.Add(-otpCache.sendQuotaWindow))
+	if err != nil {
+		return err
 	}
+	if sent >= otpCache.sendQuota {
+		return ErrOTPQuotaExceeded
+	}
+
+	return otpCache.store.RecordSend(ctx, email, now)
 }
 
 func generateOTPAndVerificationToken() (string, string, error) {
@@ -128,99 +255,192 @@ func generateOTPAndVerificationToken() (string, string, error) {
 	return verificationToken, string(buffer), nil
 }
 
-func sendMail(emailConfig *emailConfig, otp string, to []string) error {
-	// constructing the email message
-	emailConfig.body += otp
-	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", emailConfig.fromEmail, to[0], emailConfig.subject, emailConfig.body)
-
-	// authentication
-	auth := smtp.PlainAuth("", emailConfig.fromEmail, emailConfig.appPassword, emailConfig.smtpHost)
+func (otpCache *OtpCache) sendMail(ctx context.Context, purpose Purpose, otp string, to string) error {
+	// rendering the email for the otp purpose
+	subject, body, err := renderEmail(purpose, templateData{
+		OTP:              otp,
+		Email:            to,
+		ExpiresInMinutes: int(otpCache.expiresAfter.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
 
 	// sending the mail
-	err := smtp.SendMail(emailConfig.smtpHost+":"+emailConfig.smtpPort, auth, emailConfig.fromEmail, to, []byte(message))
-	if err != nil {
+	if err = otpCache.mailer.Send(ctx, mailer.Message{
+		From:    otpCache.fromEmail,
+		To:      []string{to},
+		Subject: subject,
+		Body:    body,
+	}); err != nil {
 		log.Println("Error Sending OTP: ", err)
 		return err
 	}
-	emailConfig.body = "Welcome To Learning TheArtOfSoftwareEngineering.\nYour One Time Verification Code: "
 
 	return nil
 }
 
-func (otpCache *OtpCache) SendOTP(to string) (string, error) {
+func (otpCache *OtpCache) SendOTP(ctx context.Context, to string, purpose Purpose) (string, error) {
+	to = normalizeEmail(to)
+	if err := otpCache.consumeSendQuota(ctx, to); err != nil {
+		return "", err
+	}
+
 	verificationToken, otp, err := generateOTPAndVerificationToken()
 	if err != nil {
 		return "", err
 	}
 
-	err = sendMail(otpCache.emailConfig, otp, []string{to})
+	err = otpCache.sendMail(ctx, purpose, otp, to)
 	if err != nil {
 		return "", err
 	}
-	otpCache.set(verificationToken, otp)
+	if _, err = otpCache.set(ctx, verificationToken, otp, to, purpose); err != nil {
+		return "", err
+	}
 
 	return verificationToken, nil
 }
 
-func (otpCache *OtpCache) VerifyOTP(verificationToken string, otp string) error {
-	// checking if the verification token is empty
-	if len(verificationToken) == 0 {
-		return errors.New("invalid verification token: empty")
+// SendPasswordResetOTP issues a password reset otp for any email but only sends it if the
+// email is registered. The otp of an unregistered email is stored like a real one which
+// nobody knows, so verifying it fails and locks the same way, and the email is sent in the
+// background so that the caller cannot tell registered emails apart by the response time
+func (otpCache *OtpCache) SendPasswordResetOTP(ctx context.Context, to string, registered bool) (string, error) {
+	to = normalizeEmail(to)
+	if err := otpCache.consumeSendQuota(ctx, to); err != nil {
+		return "", err
 	}
 
+	verificationToken, otp, err := generateOTPAndVerificationToken()
+	if err != nil {
+		return "", err
+	}
+	if _, err = otpCache.set(ctx, verificationToken, otp, to, PurposePasswordReset); err != nil {
+		return "", err
+	}
+
+	if registered {
+		otpCache.mailWaitGroup.Add(1)
+		go func() {
+			defer otpCache.mailWaitGroup.Done()
+
+			// the request may be finished long before the mail server answers
+			ctx, cancel := context.WithTimeout(context.Background(), passwordResetMailTimeout)
+			defer cancel()
+			if err := otpCache.sendMail(ctx, PurposePasswordReset, otp, to); err != nil {
+				log.Println("Error sending password reset otp: ", err)
+			}
+		}()
+	}
+
+	return verificationToken, nil
+}
+
+// VerifyOTP checks the otp and that the verification token was issued for the given email and purpose
+func (otpCache *OtpCache) VerifyOTP(ctx context.Context, verificationToken string, otp string, email string, purpose Purpose) error {
 	// checking if the data exist
-	data, err := otpCache.get(verificationToken)
+	data, err := otpCache.get(ctx, verificationToken)
 	if err != nil {
 		return err
 	}
 
 	// checking if the otp is expired or not
-	if time.Now().After(data.issuedAt.Add(otpCache.expiresAfter)) {
-		otpCache.delete(verificationToken)
-		return errors.New("otp expired")
+	if time.Now().After(data.IssuedAt.Add(otpCache.expiresAfter)) {
+		otpCache.delete(ctx, verificationToken)
+		return ErrOTPExpired
+	}
+
+	// checking if the verification token is locked because of too many incorrect attempts
+	if data.Attempts >= otpCache.maxVerifyAttempts {
+		return ErrOTPLocked
 	}
 
-	// checking if the otp is correct or not
-	if data.otp != otp {
-		return errors.New("incorrect otp")
+	// checking if the otp is correct and was issued for this email and purpose,
+	// a token presented for another email or action counts as an incorrect attempt
+	isBound := data.Email == normalizeEmail(email) && data.Purpose == purpose
+	if subtle.ConstantTimeCompare([]byte(data.OTP), []byte(otp)) != 1 || !isBound {
+		attempts, err := otpCache.store.IncrementAttempts(ctx, verificationToken)
+		if err != nil {
+			if errors.Is(err, errOTPDataNotFound) {
+				return ErrOTPNotFound
+			}
+			return err
+		}
+		if attempts >= otpCache.maxVerifyAttempts {
+			return ErrOTPLocked
+		}
+		if !isBound {
+			return ErrOTPMismatch
+		}
+		return ErrOTPIncorrect
+	}
+
+	// deleting the otp so that it can be used only once
+	if _, err = otpCache.delete(ctx, verificationToken); err != nil {
+		return err
 	}
-	otpCache.delete(verificationToken)
 
 	return nil
 }
 
-func (otpCache *OtpCache) ResendOTP(verificationToken string, email string) error {
-	otpCache.delete(verificationToken)
+// ResendOTP replaces the old verification token with a new one for the same email and purpose
+func (otpCache *OtpCache) ResendOTP(ctx context.Context, oldVerificationToken string, email string) (string, error) {
+	// the new otp is issued for whatever the old one was issued for
+	data, err := otpCache.get(ctx, oldVerificationToken)
+	if err != nil {
+		return "", err
+	}
+
+	// password reset otps are requested again through SendPasswordResetOTP,
+	// resending them would tell a real token apart from a decoy one
+	if data.Purpose == PurposePasswordReset {
+		return "", ErrOTPNotFound
+	}
+	email = normalizeEmail(email)
+	if data.Email != email {
+		return "", ErrOTPMismatch
+	}
+
+	if err := otpCache.consumeSendQuota(ctx, email); err != nil {
+		return "", err
+	}
+
+	otpCache.delete(ctx, oldVerificationToken)
 	verificationToken, otp, err := generateOTPAndVerificationToken()
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	err = sendMail(otpCache.emailConfig, otp, []string{email})
+	err = otpCache.sendMail(ctx, data.Purpose, otp, email)
 	if err != nil {
-		return err
+		return "", err
+	}
+	if _, err = otpCache.set(ctx, verificationToken, otp, email, data.Purpose); err != nil {
+		return "", err
 	}
-	otpCache.set(verificationToken, otp)
 
-	return nil
+	return verificationToken, nil
 }
 
-func (otpCache *OtpCache) IsResendAllowed(verificationToken string) (bool, error) {
-	// checking if verification token is empty or not
-	if len(verificationToken) == 0 {
-		return false, errors.New("invalid verification token: empty")
-	}
-
+func (otpCache *OtpCache) IsResendAllowed(ctx context.Context, verificationToken string) (bool, error) {
 	// checking if the data exist
-	data, err := otpCache.get(verificationToken)
+	data, err := otpCache.get(ctx, verificationToken)
 	if err != nil {
 		return false, err
 	}
+	if data.Purpose == PurposePasswordReset {
+		return false, ErrOTPNotFound
+	}
 
 	// checking if resend is allowed or not
-	if time.Now().Before(data.issuedAt.Add(otpCache.resendAllowedAfter)) {
-		return false, fmt.Errorf("resend allowed after %f", otpCache.resendAllowedAfter.Seconds()-float64(time.Since(data.issuedAt)))
+	if time.Now().Before(data.IssuedAt.Add(otpCache.resendAllowedAfter)) {
+		return false, fmt.Errorf("%w: resend allowed after %.0f seconds", ErrOTPResendTooSoon, time.Until(data.IssuedAt.Add(otpCache.resendAllowedAfter)).Seconds())
 	}
 
 	return true, nil
 }
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/mailer"
+)
+
+var otpPattern = regexp.MustCompile(`\b\d{6}\b`)
+
+func newTestOTPCache(t *testing.T, config Config) (*OtpCache, *mailer.Recorder) {
+	t.Helper()
+
+	recorder := mailer.NewRecorder()
+	otpCache := NewOTPCache(NewMemoryOTPStore(0), recorder, config)
+	t.Cleanup(otpCache.Close)
+
+	return otpCache, recorder
+}
+
+// lastOTP reads the otp out of the last email sent to the given address
+func lastOTP(t *testing.T, recorder *mailer.Recorder, email string) string {
+	t.Helper()
+
+	messages := recorder.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no otp email was sent")
+	}
+	message := messages[len(messages)-1]
+	if len(message.To) != 1 || message.To[0] != email {
+		t.Fatalf("otp email sent to %v, want %s", message.To, email)
+	}
+	otp := otpPattern.FindString(message.Body)
+	if otp == "" {
+		t.Fatalf("otp not found in email body %q", message.Body)
+	}
+
+	return otp
+}
+
+// issuedAgo moves the issue time of an otp into the past
+func issuedAgo(t *testing.T, otpCache *OtpCache, verificationToken string, age time.Duration) {
+	t.Helper()
+
+	ctx := context.Background()
+	data, err := otpCache.store.Get(ctx, verificationToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.IssuedAt = time.Now().Add(-age)
+	if err = otpCache.store.Set(ctx, verificationToken, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendAndVerifyOTP(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{FromEmail: "noreply@example.com"})
+
+	verificationToken, err := otpCache.SendOTP(ctx, " User@Example.com ", PurposeRegistration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := lastOTP(t, recorder, "user@example.com")
+
+	if err = otpCache.VerifyOTP(ctx, verificationToken, otp, "user@example.com", PurposeRegistration); err != nil {
+		t.Fatalf("verifying the otp: %v", err)
+	}
+
+	// an otp can be used only once
+	if err = otpCache.VerifyOTP(ctx, verificationToken, otp, "user@example.com", PurposeRegistration); !errors.Is(err, ErrOTPNotFound) {
+		t.Fatalf("verifying a used otp returned %v, want %v", err, ErrOTPNotFound)
+	}
+}
+
+func TestVerifyOTPPurposeBinding(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{})
+
+	verificationToken, err := otpCache.SendOTP(ctx, "user@example.com", PurposeEmailChange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := lastOTP(t, recorder, "user@example.com")
+
+	tests := []struct {
+		name    string
+		email   string
+		purpose Purpose
+	}{
+		{"other_purpose", "user@example.com", PurposeAccountDeletion},
+		{"other_email", "someone@example.com", PurposeEmailChange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := otpCache.VerifyOTP(ctx, verificationToken, otp, tt.email, tt.purpose); !errors.Is(err, ErrOTPMismatch) {
+				t.Errorf("got %v, want %v", err, ErrOTPMismatch)
+			}
+		})
+	}
+
+	if err = otpCache.VerifyOTP(ctx, verificationToken, otp, "user@example.com", PurposeEmailChange); err != nil {
+		t.Fatalf("verifying with the bound email and purpose: %v", err)
+	}
+}
+
+func TestVerifyOTPLockout(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{MaxVerifyAttempts: 3})
+
+	verificationToken, err := otpCache.SendOTP(ctx, "user@example.com", PurposeRegistration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := lastOTP(t, recorder, "user@example.com")
+	wrongOTP := "000000"
+	if otp == wrongOTP {
+		wrongOTP = "111111"
+	}
+
+	expected := []error{ErrOTPIncorrect, ErrOTPIncorrect, ErrOTPLocked}
+	for attempt, want := range expected {
+		if err = otpCache.VerifyOTP(ctx, verificationToken, wrongOTP, "user@example.com", PurposeRegistration); !errors.Is(err, want) {
+			t.Fatalf("attempt %d returned %v, want %v", attempt+1, err, want)
+		}
+	}
+
+	// the correct otp is refused once the token is locked
+	if err = otpCache.VerifyOTP(ctx, verificationToken, otp, "user@example.com", PurposeRegistration); !errors.Is(err, ErrOTPLocked) {
+		t.Fatalf("verifying a locked token returned %v, want %v", err, ErrOTPLocked)
+	}
+}
+
+func TestVerifyExpiredOTP(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{})
+
+	verificationToken, err := otpCache.SendOTP(ctx, "user@example.com", PurposeRegistration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otp := lastOTP(t, recorder, "user@example.com")
+	issuedAgo(t, otpCache, verificationToken, otpCache.expiresAfter+time.Second)
+
+	if err = otpCache.VerifyOTP(ctx, verificationToken, otp, "user@example.com", PurposeRegistration); !errors.Is(err, ErrOTPExpired) {
+		t.Fatalf("got %v, want %v", err, ErrOTPExpired)
+	}
+}
+
+func TestResendOTPAfterSweep(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{})
+
+	verificationToken, err := otpCache.SendOTP(ctx, "user@example.com", PurposeRegistration)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the resend cooldown applies even though the otp is still valid
+	if allowed, err := otpCache.IsResendAllowed(ctx, verificationToken); allowed || !errors.Is(err, ErrOTPResendTooSoon) {
+		t.Fatalf("resend right after sending returned %v, %v", allowed, err)
+	}
+
+	// the otp expired and the cooldown is over, the sweeper must keep it for resending
+	issuedAgo(t, otpCache, verificationToken, otpCache.resendAllowedAfter+time.Second)
+	otpCache.sweepOnce(ctx)
+	if allowed, err := otpCache.IsResendAllowed(ctx, verificationToken); !allowed {
+		t.Fatalf("resend after the cooldown refused: %v", err)
+	}
+
+	newVerificationToken, err := otpCache.ResendOTP(ctx, verificationToken, "user@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Messages()) != 2 {
+		t.Fatalf("%d emails sent, want 2", len(recorder.Messages()))
+	}
+	if err = otpCache.VerifyOTP(ctx, newVerificationToken, lastOTP(t, recorder, "user@example.com"), "user@example.com", PurposeRegistration); err != nil {
+		t.Fatalf("verifying the resent otp: %v", err)
+	}
+
+	// the old token was replaced by the new one
+	if _, err = otpCache.IsResendAllowed(ctx, verificationToken); !errors.Is(err, ErrOTPNotFound) {
+		t.Fatalf("resending a replaced token returned %v, want %v", err, ErrOTPNotFound)
+	}
+}
+
+func TestSweepRemovesAbandonedOTP(t *testing.T) {
+	ctx := context.Background()
+	otpCache, _ := newTestOTPCache(t, Config{})
+
+	verificationToken, err := otpCache.SendOTP(ctx, "user@example.com", PurposeRegistration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuedAgo(t, otpCache, verificationToken, otpCache.resendAllowedAfter+otpCache.expiresAfter+time.Second)
+	otpCache.sweepOnce(ctx)
+
+	if _, err = otpCache.IsResendAllowed(ctx, verificationToken); !errors.Is(err, ErrOTPNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrOTPNotFound)
+	}
+	if metrics := otpCache.Metrics(); metrics.ExpiredEvictions != 1 || metrics.SweepRuns != 1 {
+		t.Fatalf("unexpected metrics %+v", metrics)
+	}
+}
+
+func TestSendOTPQuota(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{SendQuota: 2})
+
+	for range 2 {
+		if _, err := otpCache.SendOTP(ctx, "user@example.com", PurposeRegistration); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := otpCache.SendOTP(ctx, "USER@example.com", PurposeRegistration); !errors.Is(err, ErrOTPQuotaExceeded) {
+		t.Fatalf("got %v, want %v", err, ErrOTPQuotaExceeded)
+	}
+	if len(recorder.Messages()) != 2 {
+		t.Fatalf("%d emails sent, want 2", len(recorder.Messages()))
+	}
+
+	// the quota is kept per email
+	if _, err := otpCache.SendOTP(ctx, "other@example.com", PurposeRegistration); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPasswordResetDecoy(t *testing.T) {
+	ctx := context.Background()
+	otpCache, recorder := newTestOTPCache(t, Config{MaxVerifyAttempts: 2})
+
+	registeredToken, err := otpCache.SendPasswordResetOTP(ctx, "user@example.com", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoyToken, err := otpCache.SendPasswordResetOTP(ctx, "nobody@example.com", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for the emails sent in the background
+	otpCache.Close()
+	if messages := recorder.Messages(); len(messages) != 1 || messages[0].To[0] != "user@example.com" {
+		t.Fatalf("emails sent %+v, want one to the registered address", messages)
+	}
+
+	// a wrong otp fails and locks the same way for registered and unregistered emails
+	tests := []struct {
+		name              string
+		verificationToken string
+		email             string
+	}{
+		{"registered", registeredToken, "user@example.com"},
+		{"unregistered", decoyToken, "nobody@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range []error{ErrOTPIncorrect, ErrOTPLocked} {
+				if err := otpCache.VerifyOTP(ctx, tt.verificationToken, "abcdef", tt.email, PurposePasswordReset); !errors.Is(err, want) {
+					t.Fatalf("got %v, want %v", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var errOTPDataNotFound = errors.New("invalid verification token: data not found")
+
+// data stored against a verification token
+type OTPData struct {
+	OTP      string
+	Email    string
+	Purpose  Purpose
+	IssuedAt time.Time
+	Attempts int
+}
+
+// OTPStore is the storage backend used by OtpCache to keep pending otps
+type OTPStore interface {
+	Set(ctx context.Context, verificationToken string, data OTPData) error
+	Get(ctx context.Context, verificationToken string) (OTPData, error)
+	Delete(ctx context.Context, verificationToken string) error
+
+	// IncrementAttempts records a failed verification and returns the updated number of failed attempts
+	IncrementAttempts(ctx context.Context, verificationToken string) (int, error)
+
+	// Expire removes every entry issued before the given time and returns the number of removed entries
+	Expire(ctx context.Context, issuedBefore time.Time) (int64, error)
+
+	// RecordSend and CountSends track the otps sent to an email for enforcing the send quota
+	RecordSend(ctx context.Context, email string, sentAt time.Time) error
+	CountSends(ctx context.Context, email string, since time.Time) (int, error)
+
+	// ExpireSends removes every send record older than the given time
+	ExpireSends(ctx context.Context, sentBefore time.Time) (int64, error)
+}
+
+// entry kept in the lru list of the memory store
+type memoryOTPEntry struct {
+	verificationToken string
+	data              OTPData
+}
+
+// in process otp store backed by a map, when maxEntries is reached
+// the least recently used entry is evicted to make room for the new one
+type memoryOTPStore struct {
+	cache             map[string]*list.Element
+	lru               *list.List
+	sends             map[string][]time.Time
+	maxEntries        int
+	capacityEvictions atomic.Int64
+	lock              sync.Mutex
+}
+
+// maxEntries <= 0 means the store is unbounded
+func NewMemoryOTPStore(maxEntries int) OTPStore {
+	return &memoryOTPStore{
+		cache:      make(map[string]*list.Element),
+		lru:        list.New(),
+		sends:      make(map[string][]time.Time),
+		maxEntries: maxEntries,
+	}
+}
+
+func (store *memoryOTPStore) Set(ctx context.Context, verificationToken string, data OTPData) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	// updating the entry if it already exist
+	if element, exists := store.cache[verificationToken]; exists {
+		element.Value.(*memoryOTPEntry).data = data
+		store.lru.MoveToFront(element)
+		return nil
+	}
+
+	// evicting least recently used entries if the store is full
+	for store.maxEntries > 0 && store.lru.Len() >= store.maxEntries {
+		store.removeElement(store.lru.Back())
+		store.capacityEvictions.Add(1)
+	}
+
+	store.cache[verificationToken] = store.lru.PushFront(&memoryOTPEntry{
+		verificationToken: verificationToken,
+		data:              data,
+	})
+	return nil
+}
+
+func (store *memoryOTPStore) Get(ctx context.Context, verificationToken string) (OTPData, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	element, exists := store.cache[verificationToken]
+	if !exists {
+		return OTPData{}, errOTPDataNotFound
+	}
+	store.lru.MoveToFront(element)
+
+	return element.Value.(*memoryOTPEntry).data, nil
+}
+
+func (store *memoryOTPStore) Delete(ctx context.Context, verificationToken string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	element, exists := store.cache[verificationToken]
+	if !exists {
+		return errOTPDataNotFound
+	}
+	store.removeElement(element)
+
+	return nil
+}
+
+func (store *memoryOTPStore) IncrementAttempts(ctx context.Context, verificationToken string) (int, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	element, exists := store.cache[verificationToken]
+	if !exists {
+		return 0, errOTPDataNotFound
+	}
+	entry := element.Value.(*memoryOTPEntry)
+	entry.data.Attempts++
+
+	return entry.data.Attempts, nil
+}
+
+func (store *memoryOTPStore) Expire(ctx context.Context, issuedBefore time.Time) (int64, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var removed int64
+	for _, element := range store.cache {
+		if element.Value.(*memoryOTPEntry).data.IssuedAt.Before(issuedBefore) {
+			store.removeElement(element)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (store *memoryOTPStore) RecordSend(ctx context.Context, email string, sentAt time.Time) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.sends[email] = append(store.sends[email], sentAt)
+	return nil
+}
+
+func (store *memoryOTPStore) CountSends(ctx context.Context, email string, since time.Time) (int, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	count := 0
+	for _, sentAt := range store.sends[email] {
+		if !sentAt.Before(since) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (store *memoryOTPStore) ExpireSends(ctx context.Context, sentBefore time.Time) (int64, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	var removed int64
+	for email, sends := range store.sends {
+		// send records are appended in order so the recent ones are at the end
+		firstRecent := len(sends)
+		for index, sentAt := range sends {
+			if !sentAt.Before(sentBefore) {
+				firstRecent = index
+				break
+			}
+		}
+		removed += int64(firstRecent)
+
+		if firstRecent == len(sends) {
+			delete(store.sends, email)
+		} else {
+			store.sends[email] = sends[firstRecent:]
+		}
+	}
+
+	return removed, nil
+}
+
+// CapacityEvictions returns the number of entries evicted because the store was full
+func (store *memoryOTPStore) CapacityEvictions() int64 {
+	return store.capacityEvictions.Load()
+}
+
+// must be called with the lock held
+func (store *memoryOTPStore) removeElement(element *list.Element) {
+	store.lru.Remove(element)
+	delete(store.cache, element.Value.(*memoryOTPEntry).verificationToken)
+}
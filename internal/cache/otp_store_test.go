@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryOTPStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryOTPStore(2)
+
+	for _, verificationToken := range []string{"first", "second"} {
+		if err := store.Set(ctx, verificationToken, OTPData{OTP: "123456", IssuedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reading the first entry makes the second one the least recently used
+	if _, err := store.Get(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ctx, "third", OTPData{OTP: "123456", IssuedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get(ctx, "second"); !errors.Is(err, errOTPDataNotFound) {
+		t.Fatalf("least recently used entry was not evicted: %v", err)
+	}
+	for _, verificationToken := range []string{"first", "third"} {
+		if _, err := store.Get(ctx, verificationToken); err != nil {
+			t.Fatalf("%s was evicted: %v", verificationToken, err)
+		}
+	}
+	if evictions := store.(*memoryOTPStore).CapacityEvictions(); evictions != 1 {
+		t.Fatalf("%d capacity evictions, want 1", evictions)
+	}
+}
+
+func TestMemoryOTPStoreExpire(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryOTPStore(0)
+	now := time.Now()
+
+	store.Set(ctx, "old", OTPData{IssuedAt: now.Add(-time.Hour)})
+	store.Set(ctx, "recent", OTPData{IssuedAt: now})
+
+	removed, err := store.Expire(ctx, now.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("%d entries removed, want 1", removed)
+	}
+	if _, err = store.Get(ctx, "recent"); err != nil {
+		t.Fatalf("recent entry was removed: %v", err)
+	}
+}
+
+func TestMemoryOTPStoreSends(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryOTPStore(0)
+	now := time.Now()
+
+	store.RecordSend(ctx, "user@example.com", now.Add(-2*time.Hour))
+	store.RecordSend(ctx, "user@example.com", now.Add(-time.Minute))
+	store.RecordSend(ctx, "user@example.com", now)
+
+	if count, _ := store.CountSends(ctx, "user@example.com", now.Add(-time.Hour)); count != 2 {
+		t.Fatalf("%d sends counted, want 2", count)
+	}
+
+	removed, err := store.ExpireSends(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Fatalf("%d send records removed, want 1", removed)
+	}
+	if count, _ := store.CountSends(ctx, "user@example.com", time.Time{}); count != 2 {
+		t.Fatalf("%d sends left, want 2", count)
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// Purpose tells what an otp is issued for
+type Purpose string
+
+const (
+	PurposeRegistration    Purpose = "registration"
+	PurposeEmailChange     Purpose = "email_change"
+	PurposePasswordChange  Purpose = "password_change"
+	PurposeAccountDeletion Purpose = "account_deletion"
+	PurposePasswordReset   Purpose = "password_reset"
+)
+
+// one template file per purpose, each defining a "subject" and a "body" template
+var purposeTemplates = map[Purpose]*template.Template{
+	PurposeRegistration:    template.Must(template.ParseFS(templateFiles, "templates/registration.tmpl")),
+	PurposeEmailChange:     template.Must(template.ParseFS(templateFiles, "templates/email_change.tmpl")),
+	PurposePasswordChange:  template.Must(template.ParseFS(templateFiles, "templates/password_change.tmpl")),
+	PurposeAccountDeletion: template.Must(template.ParseFS(templateFiles, "templates/account_deletion.tmpl")),
+	PurposePasswordReset:   template.Must(template.ParseFS(templateFiles, "templates/password_reset.tmpl")),
+}
+
+// ParsePurpose validates the purpose, an empty purpose defaults to registration.
+// password reset otps can only be requested through SendPasswordResetOTP
+func ParsePurpose(purpose string) (Purpose, error) {
+	if purpose == "" {
+		return PurposeRegistration, nil
+	}
+	if Purpose(purpose) == PurposePasswordReset {
+		return "", errors.New("invalid otp purpose")
+	}
+
+	if _, exists := purposeTemplates[Purpose(purpose)]; !exists {
+		return "", errors.New("invalid otp purpose")
+	}
+
+	return Purpose(purpose), nil
+}
+
+// data available inside the email templates
+type templateData struct {
+	OTP              string
+	Email            string
+	ExpiresInMinutes int
+}
+
+func renderEmail(purpose Purpose, data templateData) (string, string, error) {
+	emailTemplate, exists := purposeTemplates[purpose]
+	if !exists {
+		return "", "", errors.New("invalid otp purpose")
+	}
+
+	var subject, body bytes.Buffer
+	if err := emailTemplate.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := emailTemplate.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return subject.String(), body.String(), nil
+}
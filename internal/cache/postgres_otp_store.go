@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+// otp store backed by the otp_cache table so that pending otps
+// survive restarts and are shared between multiple instances
+type postgresOTPStore struct {
+	db *database.Queries
+}
+
+func NewPostgresOTPStore(db *database.Queries) OTPStore {
+	return &postgresOTPStore{
+		db: db,
+	}
+}
+
+func (store *postgresOTPStore) Set(ctx context.Context, verificationToken string, data OTPData) error {
+	return store.db.SetOTP(ctx, database.SetOTPParams{
+		VerificationToken: verificationToken,
+		Otp:               data.OTP,
+		Email:             data.Email,
+		Purpose:           string(data.Purpose),
+		IssuedAt:          data.IssuedAt.UTC(),
+		Attempts:          int32(data.Attempts),
+	})
+}
+
+func (store *postgresOTPStore) Get(ctx context.Context, verificationToken string) (OTPData, error) {
+	row, err := store.db.GetOTP(ctx, verificationToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return OTPData{}, errOTPDataNotFound
+		}
+		return OTPData{}, err
+	}
+
+	return OTPData{
+		OTP:      row.Otp,
+		Email:    row.Email,
+		Purpose:  Purpose(row.Purpose),
+		IssuedAt: row.IssuedAt,
+		Attempts: int(row.Attempts),
+	}, nil
+}
+
+func (store *postgresOTPStore) Delete(ctx context.Context, verificationToken string) error {
+	removed, err := store.db.DeleteOTP(ctx, verificationToken)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return errOTPDataNotFound
+	}
+
+	return nil
+}
+
+func (store *postgresOTPStore) Expire(ctx context.Context, issuedBefore time.Time) (int64, error) {
+	return store.db.DeleteOTPsIssuedBefore(ctx, issuedBefore.UTC())
+}
+
+func (store *postgresOTPStore) IncrementAttempts(ctx context.Context, verificationToken string) (int, error) {
+	attempts, err := store.db.IncrementOTPAttempts(ctx, verificationToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errOTPDataNotFound
+		}
+		return 0, err
+	}
+
+	return int(attempts), nil
+}
+
+func (store *postgresOTPStore) RecordSend(ctx context.Context, email string, sentAt time.Time) error {
+	return store.db.RecordOTPSend(ctx, database.RecordOTPSendParams{
+		Email:  email,
+		SentAt: sentAt.UTC(),
+	})
+}
+
+func (store *postgresOTPStore) CountSends(ctx context.Context, email string, since time.Time) (int, error) {
+	count, err := store.db.CountOTPSendsSince(ctx, database.CountOTPSendsSinceParams{
+		Email:  email,
+		SentAt: since.UTC(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+func (store *postgresOTPStore) ExpireSends(ctx context.Context, sentBefore time.Time) (int64, error) {
+	return store.db.DeleteOTPSendsBefore(ctx, sentBefore.UTC())
+}
@@ -0,0 +1,68 @@
+package contentfilter
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Verdict is the decision of a content filter on a comment
+type Verdict int
+
+const (
+	// the comment follows the normal moderation policy
+	VerdictAllow Verdict = iota
+
+	// the comment is borderline and waits in the moderation queue whatever the policy says
+	VerdictReview
+
+	// the comment is refused and not saved
+	VerdictReject
+)
+
+// reasons a comment is sent to review or rejected
+const (
+	ReasonBlockedWord   = "blocked_word"
+	ReasonFlaggedWord   = "flagged_word"
+	ReasonTooManyLinks  = "too_many_links"
+	ReasonContainsLinks = "contains_links"
+	ReasonDuplicate     = "duplicate"
+)
+
+// Reason explains one rule a comment broke
+type Reason struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result is the verdict of a filter along with every reason which led to it
+type Result struct {
+	Verdict Verdict
+	Reasons []Reason
+}
+
+// Comment is the content being created or updated
+type Comment struct {
+	UserID uuid.UUID
+
+	// id of the comment being updated, nil when it is being created
+	CommentID uuid.UUID
+
+	Description string
+}
+
+// ContentFilter checks comments before they are created or updated
+type ContentFilter interface {
+	Check(ctx context.Context, comment Comment) (Result, error)
+}
+
+// add records a broken rule and raises the verdict when the rule is more severe
+func (result *Result) add(verdict Verdict, code, message string) {
+	if verdict > result.Verdict {
+		result.Verdict = verdict
+	}
+	result.Reasons = append(result.Reasons, Reason{
+		Code:    code,
+		Message: message,
+	})
+}
@@ -0,0 +1,153 @@
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+// CommentHistory looks up the earlier comments of a user for detecting duplicates
+type CommentHistory interface {
+	HasRecentDuplicateComment(ctx context.Context, arg database.HasRecentDuplicateCommentParams) (bool, error)
+}
+
+// word list filter options, zero values are replaced by the defaults
+type Config struct {
+	// comments containing any of these words or phrases are rejected
+	BlockedWords []string
+
+	// comments containing any of these words or phrases are sent to review
+	FlaggedWords []string
+
+	// comments with more links are rejected, comments with fewer links are sent to review
+	MaxLinks int
+
+	// a comment repeating one the user posted within this window is rejected
+	DuplicateWindow time.Duration
+}
+
+// WordListFilter is the built in ContentFilter checking comments against configured
+// word lists, the number of links and the recent comments of the same user
+type WordListFilter struct {
+	history         CommentHistory
+	blockedWords    []string
+	flaggedWords    []string
+	maxLinks        int
+	duplicateWindow time.Duration
+}
+
+func NewWordListFilter(history CommentHistory, config Config) *WordListFilter {
+	filter := &WordListFilter{
+		history:         history,
+		blockedWords:    normalizeWords(config.BlockedWords),
+		flaggedWords:    normalizeWords(config.FlaggedWords),
+		maxLinks:        config.MaxLinks,
+		duplicateWindow: config.DuplicateWindow,
+	}
+	if filter.maxLinks <= 0 {
+		filter.maxLinks = 2
+	}
+	if filter.duplicateWindow <= 0 {
+		filter.duplicateWindow = 24 * time.Hour
+	}
+
+	return filter
+}
+
+// LoadWordList reads one word or phrase per line, blank lines and lines starting with # are skipped
+func LoadWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+
+	return words, scanner.Err()
+}
+
+// normalize lowercases the text and keeps only its words separated by single spaces
+func normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+func normalizeWords(words []string) []string {
+	normalized := make([]string, 0, len(words))
+	for _, word := range words {
+		if word = normalize(word); word != "" {
+			normalized = append(normalized, word)
+		}
+	}
+
+	return normalized
+}
+
+// firstMatch returns the first word or phrase of the list appearing as whole words in the normalized text
+func firstMatch(normalizedText string, words []string) (string, bool) {
+	padded := " " + normalizedText + " "
+	for _, word := range words {
+		if strings.Contains(padded, " "+word+" ") {
+			return word, true
+		}
+	}
+
+	return "", false
+}
+
+func (filter *WordListFilter) Check(ctx context.Context, comment Comment) (Result, error) {
+	result := Result{}
+	normalizedText := normalize(comment.Description)
+
+	// checking the word lists
+	if word, ok := firstMatch(normalizedText, filter.blockedWords); ok {
+		result.add(VerdictReject, ReasonBlockedWord, fmt.Sprintf("contains the blocked word %q", word))
+	}
+	if word, ok := firstMatch(normalizedText, filter.flaggedWords); ok {
+		result.add(VerdictReview, ReasonFlaggedWord, fmt.Sprintf("contains the flagged word %q", word))
+	}
+
+	// checking the number of links
+	if links := len(linkPattern.FindAllString(comment.Description, -1)); links > filter.maxLinks {
+		result.add(VerdictReject, ReasonTooManyLinks, fmt.Sprintf("contains %d links, at most %d are allowed", links, filter.maxLinks))
+	} else if links > 0 {
+		result.add(VerdictReview, ReasonContainsLinks, "contains links")
+	}
+
+	// checking if the user already posted the same comment recently, the case and
+	// whitespace of both comments are normalized by the query
+	isDuplicate, err := filter.history.HasRecentDuplicateComment(ctx, database.HasRecentDuplicateCommentParams{
+		UserID:      comment.UserID,
+		Since:       time.Now().UTC().Add(-filter.duplicateWindow),
+		Description: comment.Description,
+		ExcludeID:   uuid.NullUUID{UUID: comment.CommentID, Valid: comment.CommentID != uuid.Nil},
+	})
+	if err != nil {
+		return result, err
+	}
+	if isDuplicate {
+		result.add(VerdictReject, ReasonDuplicate, "the same comment was posted recently")
+	}
+
+	return result, nil
+}
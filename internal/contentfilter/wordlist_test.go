@@ -0,0 +1,138 @@
+package contentfilter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+// commentHistory answers duplicate lookups with a fixed result and keeps the last lookup
+type commentHistory struct {
+	isDuplicate bool
+	lastLookup  database.HasRecentDuplicateCommentParams
+}
+
+func (history *commentHistory) HasRecentDuplicateComment(ctx context.Context, arg database.HasRecentDuplicateCommentParams) (bool, error) {
+	history.lastLookup = arg
+	return history.isDuplicate, nil
+}
+
+func reasonCodes(result Result) []string {
+	codes := make([]string, 0, len(result.Reasons))
+	for _, reason := range result.Reasons {
+		codes = append(codes, reason.Code)
+	}
+
+	return codes
+}
+
+func TestWordListFilterCheck(t *testing.T) {
+	filter := NewWordListFilter(&commentHistory{}, Config{
+		BlockedWords: []string{"Scam", "buy followers"},
+		FlaggedWords: []string{"crypto"},
+		MaxLinks:     1,
+	})
+
+	tests := []struct {
+		name            string
+		description     string
+		expectedVerdict Verdict
+		expectedReasons []string
+	}{
+		{"clean", "Great write up on goroutines", VerdictAllow, []string{}},
+		{"blocked_word", "this is a SCAM!", VerdictReject, []string{ReasonBlockedWord}},
+		{"blocked_phrase_across_punctuation", "want to buy...   followers?", VerdictReject, []string{ReasonBlockedWord}},
+		{"blocked_word_inside_other_word", "scampi recipes", VerdictAllow, []string{}},
+		{"flagged_word", "thoughts on crypto", VerdictReview, []string{ReasonFlaggedWord}},
+		{"one_link", "see https://example.com/post", VerdictReview, []string{ReasonContainsLinks}},
+		{"too_many_links", "see www.example.com and http://example.org", VerdictReject, []string{ReasonTooManyLinks}},
+		{"every_rule", "crypto scam at https://a.example and https://b.example", VerdictReject, []string{ReasonBlockedWord, ReasonFlaggedWord, ReasonTooManyLinks}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filter.Check(context.Background(), Comment{
+				UserID:      uuid.New(),
+				Description: tt.description,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Verdict != tt.expectedVerdict {
+				t.Errorf("verdict %v, want %v", result.Verdict, tt.expectedVerdict)
+			}
+			if codes := reasonCodes(result); !slices.Equal(codes, tt.expectedReasons) {
+				t.Errorf("reasons %v, want %v", codes, tt.expectedReasons)
+			}
+		})
+	}
+}
+
+func TestWordListFilterDuplicates(t *testing.T) {
+	history := &commentHistory{}
+	filter := NewWordListFilter(history, Config{DuplicateWindow: time.Hour})
+
+	userID, commentID := uuid.New(), uuid.New()
+	before := time.Now().UTC().Add(-time.Hour)
+	result, err := filter.Check(context.Background(), Comment{
+		UserID:      userID,
+		CommentID:   commentID,
+		Description: "  Nice   Post ",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictAllow {
+		t.Fatalf("verdict %v, want %v", result.Verdict, VerdictAllow)
+	}
+
+	// the description is normalized by the query only, the window starts an hour ago in utc
+	lookup := history.lastLookup
+	if lookup.UserID != userID || lookup.Description != "  Nice   Post " {
+		t.Fatalf("unexpected lookup %+v", lookup)
+	}
+	if lookup.Since.Location() != time.UTC || lookup.Since.Before(before) || lookup.Since.After(time.Now().UTC().Add(-time.Hour)) {
+		t.Fatalf("lookup since %v, want an hour ago in utc", lookup.Since)
+	}
+	if lookup.ExcludeID != (uuid.NullUUID{UUID: commentID, Valid: true}) {
+		t.Fatalf("updated comment not excluded from the lookup: %+v", lookup.ExcludeID)
+	}
+
+	// new comments have nothing to exclude
+	if _, err = filter.Check(context.Background(), Comment{UserID: userID, Description: "Nice post"}); err != nil {
+		t.Fatal(err)
+	}
+	if history.lastLookup.ExcludeID.Valid {
+		t.Fatal("a new comment excluded an id from the lookup")
+	}
+
+	history.isDuplicate = true
+	result, err = filter.Check(context.Background(), Comment{UserID: userID, Description: "nice post"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verdict != VerdictReject || !slices.Equal(reasonCodes(result), []string{ReasonDuplicate}) {
+		t.Fatalf("duplicate returned %+v", result)
+	}
+}
+
+func TestLoadWordList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	if err := os.WriteFile(path, []byte("# blocked words\nscam\n\n  buy followers  \n#spam\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := LoadWordList(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"scam", "buy followers"}; !slices.Equal(words, expected) {
+		t.Fatalf("got %v, want %v", words, expected)
+	}
+}
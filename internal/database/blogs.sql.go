@@ -34,7 +34,7 @@ insert into blogs(
     NOW(),
     NOW()
 )
-returning id, title, brief, content_url, images, thumbnail_url, code_repo_link, views, author, category, created_at, updated_at, tags
+returning id, title, brief, content_url, images, thumbnail_url, code_repo_link, views, author, category, created_at, updated_at, tags, search_vector, hidden, auto_hidden, hidden_by
 `
 
 type CreateBlogParams struct {
@@ -76,6 +76,10 @@ func (q *Queries) CreateBlog(ctx context.Context, arg CreateBlogParams) (Blog, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		pq.Array(&i.Tags),
+		&i.SearchVector,
+		&i.Hidden,
+		&i.AutoHidden,
+		&i.HiddenBy,
 	)
 	return i, err
 }
@@ -94,16 +98,277 @@ func (q *Queries) DislikeBlog(ctx context.Context, arg DislikeBlogParams) error
 	return err
 }
 
+const filterBlogsByLikes = `-- name: FilterBlogsByLikes :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at, blog_likes.like_count
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+cross join lateral (select count(*) as like_count from likes where likes.blog_id = blogs.id) as blog_likes
+where not blogs.hidden
+and ($1::text is null or categories.category = $1::text)
+and ($2::text[] is null or blogs.tags && $2::text[])
+and ($3::text[] is null or blogs.tags @> $3::text[])
+and ($4::text is null or users.username = $4::text)
+and ($5::timestamp is null or blogs.created_at >= $5::timestamp)
+and ($6::timestamp is null or blogs.created_at < $6::timestamp)
+and ($7::boolean is null or (blogs.code_repo_link is not null) = $7::boolean)
+and ($8::bigint is null or (blog_likes.like_count, blogs.id) < ($8::bigint, $9::uuid))
+order by blog_likes.like_count desc, blogs.id desc
+limit $10
+`
+
+type FilterBlogsByLikesParams struct {
+	Category    sql.NullString
+	AnyTags     []string
+	AllTags     []string
+	Author      sql.NullString
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	HasCodeRepo sql.NullBool
+	AfterLikes  sql.NullInt64
+	AfterID     uuid.NullUUID
+	PageLimit   int32
+}
+
+type FilterBlogsByLikesRow struct {
+	ID           uuid.UUID
+	Title        string
+	Brief        string
+	ThumbnailUrl string
+	Views        int32
+	Tags         []string
+	CreatedAt    time.Time
+	LikeCount    int64
+}
+
+func (q *Queries) FilterBlogsByLikes(ctx context.Context, arg FilterBlogsByLikesParams) ([]FilterBlogsByLikesRow, error) {
+	rows, err := q.db.QueryContext(ctx, filterBlogsByLikes,
+		arg.Category,
+		pq.Array(arg.AnyTags),
+		pq.Array(arg.AllTags),
+		arg.Author,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.HasCodeRepo,
+		arg.AfterLikes,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterBlogsByLikesRow
+	for rows.Next() {
+		var i FilterBlogsByLikesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Brief,
+			&i.ThumbnailUrl,
+			&i.Views,
+			pq.Array(&i.Tags),
+			&i.CreatedAt,
+			&i.LikeCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const filterBlogsByNewest = `-- name: FilterBlogsByNewest :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+where not blogs.hidden
+and ($1::text is null or categories.category = $1::text)
+and ($2::text[] is null or blogs.tags && $2::text[])
+and ($3::text[] is null or blogs.tags @> $3::text[])
+and ($4::text is null or users.username = $4::text)
+and ($5::timestamp is null or blogs.created_at >= $5::timestamp)
+and ($6::timestamp is null or blogs.created_at < $6::timestamp)
+and ($7::boolean is null or (blogs.code_repo_link is not null) = $7::boolean)
+and ($8::timestamp is null or (blogs.created_at, blogs.id) < ($8::timestamp, $9::uuid))
+order by blogs.created_at desc, blogs.id desc
+limit $10
+`
+
+type FilterBlogsByNewestParams struct {
+	Category       sql.NullString
+	AnyTags        []string
+	AllTags        []string
+	Author         sql.NullString
+	CreatedFrom    sql.NullTime
+	CreatedTo      sql.NullTime
+	HasCodeRepo    sql.NullBool
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
+}
+
+type FilterBlogsByNewestRow struct {
+	ID           uuid.UUID
+	Title        string
+	Brief        string
+	ThumbnailUrl string
+	Views        int32
+	Tags         []string
+	CreatedAt    time.Time
+}
+
+func (q *Queries) FilterBlogsByNewest(ctx context.Context, arg FilterBlogsByNewestParams) ([]FilterBlogsByNewestRow, error) {
+	rows, err := q.db.QueryContext(ctx, filterBlogsByNewest,
+		arg.Category,
+		pq.Array(arg.AnyTags),
+		pq.Array(arg.AllTags),
+		arg.Author,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.HasCodeRepo,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterBlogsByNewestRow
+	for rows.Next() {
+		var i FilterBlogsByNewestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Brief,
+			&i.ThumbnailUrl,
+			&i.Views,
+			pq.Array(&i.Tags),
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const filterBlogsByViews = `-- name: FilterBlogsByViews :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+where not blogs.hidden
+and ($1::text is null or categories.category = $1::text)
+and ($2::text[] is null or blogs.tags && $2::text[])
+and ($3::text[] is null or blogs.tags @> $3::text[])
+and ($4::text is null or users.username = $4::text)
+and ($5::timestamp is null or blogs.created_at >= $5::timestamp)
+and ($6::timestamp is null or blogs.created_at < $6::timestamp)
+and ($7::boolean is null or (blogs.code_repo_link is not null) = $7::boolean)
+and ($8::int is null or (blogs.views, blogs.id) < ($8::int, $9::uuid))
+order by blogs.views desc, blogs.id desc
+limit $10
+`
+
+type FilterBlogsByViewsParams struct {
+	Category    sql.NullString
+	AnyTags     []string
+	AllTags     []string
+	Author      sql.NullString
+	CreatedFrom sql.NullTime
+	CreatedTo   sql.NullTime
+	HasCodeRepo sql.NullBool
+	AfterViews  sql.NullInt32
+	AfterID     uuid.NullUUID
+	PageLimit   int32
+}
+
+type FilterBlogsByViewsRow struct {
+	ID           uuid.UUID
+	Title        string
+	Brief        string
+	ThumbnailUrl string
+	Views        int32
+	Tags         []string
+	CreatedAt    time.Time
+}
+
+func (q *Queries) FilterBlogsByViews(ctx context.Context, arg FilterBlogsByViewsParams) ([]FilterBlogsByViewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, filterBlogsByViews,
+		arg.Category,
+		pq.Array(arg.AnyTags),
+		pq.Array(arg.AllTags),
+		arg.Author,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.HasCodeRepo,
+		arg.AfterViews,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FilterBlogsByViewsRow
+	for rows.Next() {
+		var i FilterBlogsByViewsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Brief,
+			&i.ThumbnailUrl,
+			&i.Views,
+			pq.Array(&i.Tags),
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getAllBlogsByCategory = `-- name: GetAllBlogsByCategory :many
 select 
 id, title, brief, thumbnail_url, views,
-tags, created_at from blogs where category = $1 and created_at < $2 limit $3
+tags, created_at from blogs where category = $1 and not hidden
+and ($2::timestamp is null or created_at < $2::timestamp)
+and ($3::timestamp is null or (created_at, id) < ($3::timestamp, $4::uuid))
+order by created_at desc, id desc
+limit $5
 `
 
 type GetAllBlogsByCategoryParams struct {
-	Category  uuid.UUID
-	CreatedAt time.Time
-	Limit     int32
+	Category       uuid.UUID
+	Before         sql.NullTime
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
 }
 
 type GetAllBlogsByCategoryRow struct {
@@ -117,7 +382,13 @@ type GetAllBlogsByCategoryRow struct {
 }
 
 func (q *Queries) GetAllBlogsByCategory(ctx context.Context, arg GetAllBlogsByCategoryParams) ([]GetAllBlogsByCategoryRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllBlogsByCategory, arg.Category, arg.CreatedAt, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, getAllBlogsByCategory,
+		arg.Category,
+		arg.Before,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -147,11 +418,22 @@ func (q *Queries) GetAllBlogsByCategory(ctx context.Context, arg GetAllBlogsByCa
 	return items, nil
 }
 
+const getBlogAuthorByID = `-- name: GetBlogAuthorByID :one
+select author from blogs where id = $1
+`
+
+func (q *Queries) GetBlogAuthorByID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getBlogAuthorByID, id)
+	var author uuid.UUID
+	err := row.Scan(&author)
+	return author, err
+}
+
 const getBlogByID = `-- name: GetBlogByID :one
 select
 blogs.title, blogs.brief, blogs.content_url, blogs.images, blogs.thumbnail_url,
 blogs.code_repo_link, blogs.views,
-blogs.tags, users.username, blogs.created_at
+blogs.tags, users.username, blogs.created_at, blogs.hidden
 from blogs join users on blogs.author = users.id where blogs.id = $1
 `
 
@@ -166,6 +448,7 @@ type GetBlogByIDRow struct {
 	Tags         []string
 	Username     string
 	CreatedAt    time.Time
+	Hidden       bool
 }
 
 func (q *Queries) GetBlogByID(ctx context.Context, id uuid.UUID) (GetBlogByIDRow, error) {
@@ -182,10 +465,27 @@ func (q *Queries) GetBlogByID(ctx context.Context, id uuid.UUID) (GetBlogByIDRow
 		pq.Array(&i.Tags),
 		&i.Username,
 		&i.CreatedAt,
+		&i.Hidden,
 	)
 	return i, err
 }
 
+const getBlogHideState = `-- name: GetBlogHideState :one
+select hidden, auto_hidden from blogs where id = $1
+`
+
+type GetBlogHideStateRow struct {
+	Hidden     bool
+	AutoHidden bool
+}
+
+func (q *Queries) GetBlogHideState(ctx context.Context, id uuid.UUID) (GetBlogHideStateRow, error) {
+	row := q.db.QueryRowContext(ctx, getBlogHideState, id)
+	var i GetBlogHideStateRow
+	err := row.Scan(&i.Hidden, &i.AutoHidden)
+	return i, err
+}
+
 const getNumberOfLikes = `-- name: GetNumberOfLikes :one
 select count(*) as noOfLikes from likes where blog_id = $1
 `
@@ -295,3 +595,32 @@ func (q *Queries) UpdateBlog(ctx context.Context, arg UpdateBlogParams) (UpdateB
 	err := row.Scan(&i.CreatedAt, &i.UpdatedAt)
 	return i, err
 }
+
+const updateBlogHideState = `-- name: UpdateBlogHideState :execrows
+update blogs set hidden = $1, auto_hidden = $2, hidden_by = $3
+where id = $4 and hidden = $5 and auto_hidden = $6
+`
+
+type UpdateBlogHideStateParams struct {
+	Hidden            bool
+	AutoHidden        bool
+	HiddenBy          uuid.NullUUID
+	ID                uuid.UUID
+	CurrentHidden     bool
+	CurrentAutoHidden bool
+}
+
+func (q *Queries) UpdateBlogHideState(ctx context.Context, arg UpdateBlogHideStateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateBlogHideState,
+		arg.Hidden,
+		arg.AutoHidden,
+		arg.HiddenBy,
+		arg.ID,
+		arg.CurrentHidden,
+		arg.CurrentAutoHidden,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -7,6 +7,8 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/lib/pq"
@@ -26,7 +28,7 @@ insert into books(
     NOW(),
     NOW()
 )
-returning id, name, cover_image_url, review, level, created_at, updated_at, tags
+returning id, name, cover_image_url, review, level, created_at, updated_at, tags, search_vector
 `
 
 type CreateBookParams struct {
@@ -55,22 +57,33 @@ func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, e
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		pq.Array(&i.Tags),
+		&i.SearchVector,
 	)
 	return i, err
 }
 
 const getAllBooks = `-- name: GetAllBooks :many
-select id, name, cover_image_url from books
+select id, name, cover_image_url, created_at from books
+where $1::timestamp is null or (created_at, id) < ($1::timestamp, $2::uuid)
+order by created_at desc, id desc
+limit $3
 `
 
+type GetAllBooksParams struct {
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
+}
+
 type GetAllBooksRow struct {
 	ID            uuid.UUID
 	Name          string
 	CoverImageUrl string
+	CreatedAt     time.Time
 }
 
-func (q *Queries) GetAllBooks(ctx context.Context) ([]GetAllBooksRow, error) {
-	rows, err := q.db.QueryContext(ctx, getAllBooks)
+func (q *Queries) GetAllBooks(ctx context.Context, arg GetAllBooksParams) ([]GetAllBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getAllBooks, arg.AfterCreatedAt, arg.AfterID, arg.PageLimit)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +91,12 @@ func (q *Queries) GetAllBooks(ctx context.Context) ([]GetAllBooksRow, error) {
 	var items []GetAllBooksRow
 	for rows.Next() {
 		var i GetAllBooksRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.CoverImageUrl); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CoverImageUrl,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -129,17 +147,34 @@ func (q *Queries) GetBookByID(ctx context.Context, id uuid.UUID) (GetBookByIDRow
 }
 
 const getBooksByLevel = `-- name: GetBooksByLevel :many
-select id, name, cover_image_url from books where books.level = (select id from book_level where book_level.level = $1)
+select id, name, cover_image_url, created_at from books
+where books.level = (select id from book_level where book_level.level = $1)
+and ($2::timestamp is null or (created_at, id) < ($2::timestamp, $3::uuid))
+order by created_at desc, id desc
+limit $4
 `
 
+type GetBooksByLevelParams struct {
+	Level          string
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
+}
+
 type GetBooksByLevelRow struct {
 	ID            uuid.UUID
 	Name          string
 	CoverImageUrl string
+	CreatedAt     time.Time
 }
 
-func (q *Queries) GetBooksByLevel(ctx context.Context, level string) ([]GetBooksByLevelRow, error) {
-	rows, err := q.db.QueryContext(ctx, getBooksByLevel, level)
+func (q *Queries) GetBooksByLevel(ctx context.Context, arg GetBooksByLevelParams) ([]GetBooksByLevelRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBooksByLevel,
+		arg.Level,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +182,12 @@ func (q *Queries) GetBooksByLevel(ctx context.Context, level string) ([]GetBooks
 	var items []GetBooksByLevelRow
 	for rows.Next() {
 		var i GetBooksByLevelRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.CoverImageUrl); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CoverImageUrl,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
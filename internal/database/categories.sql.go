@@ -37,7 +37,7 @@ func (q *Queries) CreateCategory(ctx context.Context, category string) (CreateCa
 }
 
 const getAllCategories = `-- name: GetAllCategories :many
-select id, category, created_at, updated_at from categories
+select id, category, created_at, updated_at from categories order by category
 `
 
 func (q *Queries) GetAllCategories(ctx context.Context) ([]Category, error) {
@@ -7,54 +7,139 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countApprovedCommentsByUser = `-- name: CountApprovedCommentsByUser :one
+select count(*) from comments where user_id = $1 and status = 'approved'
+`
+
+func (q *Queries) CountApprovedCommentsByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countApprovedCommentsByUser, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createComment = `-- name: CreateComment :one
 insert into comments(
     id, description, user_id, blog_id,
-    created_at, updated_at
+    parent_id, depth, status, created_at, updated_at
 ) values(
     gen_random_uuid(),
-    $1, $2, $3, NOW(), NOW()
+    $1, $2, $3, $4, $5, $6, NOW(), NOW()
 )
-returning id, description, created_at, updated_at
+returning id, description, parent_id, depth, status, created_at, updated_at
 `
 
 type CreateCommentParams struct {
 	Description string
 	UserID      uuid.UUID
 	BlogID      uuid.UUID
+	ParentID    uuid.NullUUID
+	Depth       int32
+	Status      string
 }
 
 type CreateCommentRow struct {
 	ID          uuid.UUID
 	Description string
+	ParentID    uuid.NullUUID
+	Depth       int32
+	Status      string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
 
 func (q *Queries) CreateComment(ctx context.Context, arg CreateCommentParams) (CreateCommentRow, error) {
-	row := q.db.QueryRowContext(ctx, createComment, arg.Description, arg.UserID, arg.BlogID)
+	row := q.db.QueryRowContext(ctx, createComment,
+		arg.Description,
+		arg.UserID,
+		arg.BlogID,
+		arg.ParentID,
+		arg.Depth,
+		arg.Status,
+	)
 	var i CreateCommentRow
 	err := row.Scan(
 		&i.ID,
 		&i.Description,
+		&i.ParentID,
+		&i.Depth,
+		&i.Status,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const dislikeComment = `-- name: DislikeComment :one
+with disliked as (
+    delete from comment_likes using comments
+    where comment_likes.comment_id = comments.id and comment_likes.user_id = $2 and comment_likes.comment_id = $1
+    and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+    returning comment_likes.comment_id
+)
+update comments set likes = likes - (select count(*) from disliked)
+where comments.id = $1 and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+returning likes
+`
+
+type DislikeCommentParams struct {
+	CommentID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) DislikeComment(ctx context.Context, arg DislikeCommentParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, dislikeComment, arg.CommentID, arg.UserID)
+	var likes int32
+	err := row.Scan(&likes)
+	return likes, err
+}
+
+const getCommentAuthorByID = `-- name: GetCommentAuthorByID :one
+select user_id from comments where id = $1
+`
+
+func (q *Queries) GetCommentAuthorByID(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getCommentAuthorByID, id)
+	var user_id uuid.UUID
+	err := row.Scan(&user_id)
+	return user_id, err
+}
+
 const getCommentByBlogID = `-- name: GetCommentByBlogID :many
 select
-comments.id, comments.description, users.username,
-users.profile_pic_url, comments.created_at, comments.updated_at
-from comments join users on comments.user_id = users.id where comments.blog_id = $1
+comments.id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as replies where replies.parent_id = comments.id and replies.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = $1::uuid) as has_user_liked
+from comments join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where comments.blog_id = $2 and comments.parent_id is null
+and comments.status in ('approved', 'hidden') and not blogs.hidden
+and ($3::timestamp is null or (comments.created_at, comments.id) > ($3::timestamp, $4::uuid))
+order by comments.created_at, comments.id
+limit $5
 `
 
+type GetCommentByBlogIDParams struct {
+	ViewerID       uuid.NullUUID
+	BlogID         uuid.UUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
+}
+
 type GetCommentByBlogIDRow struct {
 	ID            uuid.UUID
 	Description   string
@@ -62,10 +147,20 @@ type GetCommentByBlogIDRow struct {
 	ProfilePicUrl string
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
+	ReplyCount    int64
+	Removed       bool
+	LikesCount    int32
+	HasUserLiked  bool
 }
 
-func (q *Queries) GetCommentByBlogID(ctx context.Context, blogID uuid.UUID) ([]GetCommentByBlogIDRow, error) {
-	rows, err := q.db.QueryContext(ctx, getCommentByBlogID, blogID)
+func (q *Queries) GetCommentByBlogID(ctx context.Context, arg GetCommentByBlogIDParams) ([]GetCommentByBlogIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentByBlogID,
+		arg.ViewerID,
+		arg.BlogID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +175,271 @@ func (q *Queries) GetCommentByBlogID(ctx context.Context, blogID uuid.UUID) ([]G
 			&i.ProfilePicUrl,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ReplyCount,
+			&i.Removed,
+			&i.LikesCount,
+			&i.HasUserLiked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentHideState = `-- name: GetCommentHideState :one
+select status, status_before_hide from comments where id = $1 and deleted_at is null
+`
+
+type GetCommentHideStateRow struct {
+	Status           string
+	StatusBeforeHide sql.NullString
+}
+
+func (q *Queries) GetCommentHideState(ctx context.Context, id uuid.UUID) (GetCommentHideStateRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentHideState, id)
+	var i GetCommentHideStateRow
+	err := row.Scan(&i.Status, &i.StatusBeforeHide)
+	return i, err
+}
+
+const getCommentRepliesByRootIDs = `-- name: GetCommentRepliesByRootIDs :many
+with recursive replies as (
+    select comments.id from comments
+    where comments.parent_id = any($2::uuid[]) and comments.status in ('approved', 'hidden')
+    union all
+    select comments.id from comments join replies on comments.parent_id = replies.id
+    where comments.status in ('approved', 'hidden')
+)
+select
+comments.id, comments.parent_id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+comments.depth,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as children where children.parent_id = comments.id and children.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = $1::uuid) as has_user_liked
+from replies
+join comments on replies.id = comments.id
+join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where not blogs.hidden
+order by comments.created_at, comments.id
+`
+
+type GetCommentRepliesByRootIDsParams struct {
+	ViewerID uuid.NullUUID
+	RootIds  []uuid.UUID
+}
+
+type GetCommentRepliesByRootIDsRow struct {
+	ID            uuid.UUID
+	ParentID      uuid.NullUUID
+	Description   string
+	Depth         int32
+	Username      string
+	ProfilePicUrl string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ReplyCount    int64
+	Removed       bool
+	LikesCount    int32
+	HasUserLiked  bool
+}
+
+func (q *Queries) GetCommentRepliesByRootIDs(ctx context.Context, arg GetCommentRepliesByRootIDsParams) ([]GetCommentRepliesByRootIDsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getCommentRepliesByRootIDs, arg.ViewerID, pq.Array(arg.RootIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCommentRepliesByRootIDsRow
+	for rows.Next() {
+		var i GetCommentRepliesByRootIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ParentID,
+			&i.Description,
+			&i.Depth,
+			&i.Username,
+			&i.ProfilePicUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyCount,
+			&i.Removed,
+			&i.LikesCount,
+			&i.HasUserLiked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getCommentThreadInfo = `-- name: GetCommentThreadInfo :one
+select blog_id, depth, status, deleted_at from comments where id = $1
+`
+
+type GetCommentThreadInfoRow struct {
+	BlogID    uuid.UUID
+	Depth     int32
+	Status    string
+	DeletedAt sql.NullTime
+}
+
+func (q *Queries) GetCommentThreadInfo(ctx context.Context, id uuid.UUID) (GetCommentThreadInfoRow, error) {
+	row := q.db.QueryRowContext(ctx, getCommentThreadInfo, id)
+	var i GetCommentThreadInfoRow
+	err := row.Scan(
+		&i.BlogID,
+		&i.Depth,
+		&i.Status,
+		&i.DeletedAt,
+	)
+	return i, err
+}
+
+const getModerationQueue = `-- name: GetModerationQueue :many
+select
+comments.id, comments.blog_id, comments.parent_id, comments.description,
+users.username, comments.created_at, comments.updated_at
+from comments join users on comments.user_id = users.id
+where comments.status = 'pending' and comments.deleted_at is null
+and ($1::timestamp is null or (comments.created_at, comments.id) > ($1::timestamp, $2::uuid))
+order by comments.created_at, comments.id
+limit $3
+`
+
+type GetModerationQueueParams struct {
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	PageLimit      int32
+}
+
+type GetModerationQueueRow struct {
+	ID          uuid.UUID
+	BlogID      uuid.UUID
+	ParentID    uuid.NullUUID
+	Description string
+	Username    string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (q *Queries) GetModerationQueue(ctx context.Context, arg GetModerationQueueParams) ([]GetModerationQueueRow, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationQueue, arg.AfterCreatedAt, arg.AfterID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetModerationQueueRow
+	for rows.Next() {
+		var i GetModerationQueueRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BlogID,
+			&i.ParentID,
+			&i.Description,
+			&i.Username,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTopCommentsByBlogID = `-- name: GetTopCommentsByBlogID :many
+select
+comments.id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as replies where replies.parent_id = comments.id and replies.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = $1::uuid) as has_user_liked
+from comments join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where comments.blog_id = $2 and comments.parent_id is null
+and comments.status in ('approved', 'hidden') and not blogs.hidden
+and ($3::int is null or (comments.likes, comments.id) < ($3::int, $4::uuid))
+order by comments.likes desc, comments.id desc
+limit $5
+`
+
+type GetTopCommentsByBlogIDParams struct {
+	ViewerID   uuid.NullUUID
+	BlogID     uuid.UUID
+	AfterLikes sql.NullInt32
+	AfterID    uuid.NullUUID
+	PageLimit  int32
+}
+
+type GetTopCommentsByBlogIDRow struct {
+	ID            uuid.UUID
+	Description   string
+	Username      string
+	ProfilePicUrl string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ReplyCount    int64
+	Removed       bool
+	LikesCount    int32
+	HasUserLiked  bool
+}
+
+func (q *Queries) GetTopCommentsByBlogID(ctx context.Context, arg GetTopCommentsByBlogIDParams) ([]GetTopCommentsByBlogIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getTopCommentsByBlogID,
+		arg.ViewerID,
+		arg.BlogID,
+		arg.AfterLikes,
+		arg.AfterID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTopCommentsByBlogIDRow
+	for rows.Next() {
+		var i GetTopCommentsByBlogIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Description,
+			&i.Username,
+			&i.ProfilePicUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ReplyCount,
+			&i.Removed,
+			&i.LikesCount,
+			&i.HasUserLiked,
 		); err != nil {
 			return nil, err
 		}
@@ -94,8 +454,78 @@ func (q *Queries) GetCommentByBlogID(ctx context.Context, blogID uuid.UUID) ([]G
 	return items, nil
 }
 
-const removeComment = `-- name: RemoveComment :exec
-delete from comments where id = $1 and user_id = $2
+const hasRecentDuplicateComment = `-- name: HasRecentDuplicateComment :one
+select exists(
+    select 1 from comments
+    where user_id = $1 and created_at > $2::timestamp and deleted_at is null
+    and lower(regexp_replace(trim(description), '\s+', ' ', 'g')) = lower(regexp_replace(trim($3::text), '\s+', ' ', 'g'))
+    and ($4::uuid is null or id <> $4::uuid)
+)
+`
+
+type HasRecentDuplicateCommentParams struct {
+	UserID      uuid.UUID
+	Since       time.Time
+	Description string
+	ExcludeID   uuid.NullUUID
+}
+
+func (q *Queries) HasRecentDuplicateComment(ctx context.Context, arg HasRecentDuplicateCommentParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasRecentDuplicateComment,
+		arg.UserID,
+		arg.Since,
+		arg.Description,
+		arg.ExcludeID,
+	)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const hasUserLikedComment = `-- name: HasUserLikedComment :one
+select exists(select 1 from comment_likes where user_id = $1 and comment_id = $2)
+`
+
+type HasUserLikedCommentParams struct {
+	UserID    uuid.UUID
+	CommentID uuid.UUID
+}
+
+func (q *Queries) HasUserLikedComment(ctx context.Context, arg HasUserLikedCommentParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, hasUserLikedComment, arg.UserID, arg.CommentID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const likeComment = `-- name: LikeComment :one
+with liked as (
+    insert into comment_likes(user_id, comment_id, created_at, updated_at)
+    select $2::uuid, comments.id, NOW(), NOW() from comments
+    where comments.id = $1 and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+    on conflict do nothing
+    returning comment_id
+)
+update comments set likes = likes + (select count(*) from liked)
+where comments.id = $1 and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+returning likes
+`
+
+type LikeCommentParams struct {
+	CommentID uuid.UUID
+	UserID    uuid.UUID
+}
+
+func (q *Queries) LikeComment(ctx context.Context, arg LikeCommentParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, likeComment, arg.CommentID, arg.UserID)
+	var likes int32
+	err := row.Scan(&likes)
+	return likes, err
+}
+
+const removeComment = `-- name: RemoveComment :execrows
+update comments set deleted_at = NOW(), updated_at = NOW()
+where id = $1 and user_id = $2 and deleted_at is null
 `
 
 type RemoveCommentParams struct {
@@ -103,30 +533,101 @@ type RemoveCommentParams struct {
 	UserID uuid.UUID
 }
 
-func (q *Queries) RemoveComment(ctx context.Context, arg RemoveCommentParams) error {
-	_, err := q.db.ExecContext(ctx, removeComment, arg.ID, arg.UserID)
-	return err
+func (q *Queries) RemoveComment(ctx context.Context, arg RemoveCommentParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeComment, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setCommentsStatus = `-- name: SetCommentsStatus :many
+update comments set status = $1, status_before_hide = null, moderated_by = $2, moderated_at = NOW()
+where id = any($3::uuid[]) and deleted_at is null
+returning id
+`
+
+type SetCommentsStatusParams struct {
+	Status      string
+	ModeratedBy uuid.NullUUID
+	Ids         []uuid.UUID
+}
+
+func (q *Queries) SetCommentsStatus(ctx context.Context, arg SetCommentsStatusParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, setCommentsStatus, arg.Status, arg.ModeratedBy, pq.Array(arg.Ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const updateCommentByID = `-- name: UpdateCommentByID :one
-update comments set description = $1, updated_at = NOW() where id = $2 and user_id = $3
-returning description, updated_at
+update comments set description = $1, status = coalesce($2, status), updated_at = NOW()
+where id = $3 and user_id = $4 and deleted_at is null
+returning description, status, updated_at
 `
 
 type UpdateCommentByIDParams struct {
 	Description string
+	Status      sql.NullString
 	ID          uuid.UUID
 	UserID      uuid.UUID
 }
 
 type UpdateCommentByIDRow struct {
 	Description string
+	Status      string
 	UpdatedAt   time.Time
 }
 
 func (q *Queries) UpdateCommentByID(ctx context.Context, arg UpdateCommentByIDParams) (UpdateCommentByIDRow, error) {
-	row := q.db.QueryRowContext(ctx, updateCommentByID, arg.Description, arg.ID, arg.UserID)
+	row := q.db.QueryRowContext(ctx, updateCommentByID,
+		arg.Description,
+		arg.Status,
+		arg.ID,
+		arg.UserID,
+	)
 	var i UpdateCommentByIDRow
-	err := row.Scan(&i.Description, &i.UpdatedAt)
+	err := row.Scan(&i.Description, &i.Status, &i.UpdatedAt)
 	return i, err
 }
+
+const updateCommentHideState = `-- name: UpdateCommentHideState :execrows
+update comments set status = $1, status_before_hide = $2
+where id = $3 and status = $4 and deleted_at is null
+`
+
+type UpdateCommentHideStateParams struct {
+	Status           string
+	StatusBeforeHide sql.NullString
+	ID               uuid.UUID
+	CurrentStatus    string
+}
+
+func (q *Queries) UpdateCommentHideState(ctx context.Context, arg UpdateCommentHideStateParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateCommentHideState,
+		arg.Status,
+		arg.StatusBeforeHide,
+		arg.ID,
+		arg.CurrentStatus,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: login_attempts.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const clearLoginFailures = `-- name: ClearLoginFailures :execrows
+delete from login_attempts where scope = $1 and identifier = $2
+`
+
+type ClearLoginFailuresParams struct {
+	Scope      string
+	Identifier string
+}
+
+func (q *Queries) ClearLoginFailures(ctx context.Context, arg ClearLoginFailuresParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, clearLoginFailures, arg.Scope, arg.Identifier)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getLoginLockout = `-- name: GetLoginLockout :one
+select locked_until from login_attempts where scope = $1 and identifier = $2
+`
+
+type GetLoginLockoutParams struct {
+	Scope      string
+	Identifier string
+}
+
+func (q *Queries) GetLoginLockout(ctx context.Context, arg GetLoginLockoutParams) (sql.NullTime, error) {
+	row := q.db.QueryRowContext(ctx, getLoginLockout, arg.Scope, arg.Identifier)
+	var locked_until sql.NullTime
+	err := row.Scan(&locked_until)
+	return locked_until, err
+}
+
+const lockLogin = `-- name: LockLogin :exec
+update login_attempts set locked_until = $1 where scope = $2 and identifier = $3
+`
+
+type LockLoginParams struct {
+	LockedUntil sql.NullTime
+	Scope       string
+	Identifier  string
+}
+
+func (q *Queries) LockLogin(ctx context.Context, arg LockLoginParams) error {
+	_, err := q.db.ExecContext(ctx, lockLogin, arg.LockedUntil, arg.Scope, arg.Identifier)
+	return err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+insert into login_attempts(scope, identifier, failed_count, last_failed_at)
+values($1, $2, 1, $3)
+on conflict(scope, identifier) do update set
+    failed_count = case when login_attempts.last_failed_at < $4 then 1 else login_attempts.failed_count + 1 end,
+    last_failed_at = excluded.last_failed_at
+returning failed_count
+`
+
+type RecordLoginFailureParams struct {
+	Scope        string
+	Identifier   string
+	LastFailedAt time.Time
+	WindowStart  time.Time
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure,
+		arg.Scope,
+		arg.Identifier,
+		arg.LastFailedAt,
+		arg.WindowStart,
+	)
+	var failed_count int32
+	err := row.Scan(&failed_count)
+	return failed_count, err
+}
@@ -26,6 +26,10 @@ type Blog struct {
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	Tags         []string
+	SearchVector interface{}
+	Hidden       bool
+	AutoHidden   bool
+	HiddenBy     uuid.NullUUID
 }
 
 type Book struct {
@@ -37,6 +41,7 @@ type Book struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 	Tags          []string
+	SearchVector  interface{}
 }
 
 type BookLevel struct {
@@ -54,13 +59,27 @@ type Category struct {
 }
 
 type Comment struct {
-	ID          uuid.UUID
-	Description string
-	Likes       int32
-	UserID      uuid.UUID
-	BlogID      uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID               uuid.UUID
+	Description      string
+	Likes            int32
+	UserID           uuid.UUID
+	BlogID           uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	ParentID         uuid.NullUUID
+	Depth            int32
+	Status           string
+	ModeratedBy      uuid.NullUUID
+	ModeratedAt      sql.NullTime
+	DeletedAt        sql.NullTime
+	StatusBeforeHide sql.NullString
+}
+
+type CommentLike struct {
+	UserID    uuid.UUID
+	CommentID uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 type Like struct {
@@ -70,19 +89,88 @@ type Like struct {
 	UpdatedAt time.Time
 }
 
+type LoginAttempt struct {
+	Scope        string
+	Identifier   string
+	FailedCount  int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type OtpCache struct {
+	VerificationToken string
+	Otp               string
+	IssuedAt          time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Attempts          int32
+	Email             string
+	Purpose           string
+}
+
+type OtpSendLog struct {
+	Email  string
+	SentAt time.Time
+}
+
 type RefreshToken struct {
-	Token     string
+	TokenHash string
+	SessionID uuid.UUID
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	UsedAt    sql.NullTime
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+type Report struct {
+	ID         uuid.UUID
+	ReporterID uuid.UUID
+	TargetType string
+	TargetID   uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	Resolution sql.NullString
+	ResolvedBy uuid.NullUUID
+	ResolvedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
 type Role struct {
 	ID        uuid.UUID
 	RoleName  string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	IsBuiltin bool
+}
+
+type RoleAuditLog struct {
+	ID           uuid.UUID
+	ActorID      uuid.NullUUID
+	Action       string
+	RoleID       uuid.UUID
+	TargetUserID uuid.NullUUID
+	Details      string
+	CreatedAt    time.Time
+}
+
+type RolePermission struct {
+	RoleID     uuid.UUID
+	Permission string
+	CreatedAt  time.Time
+}
+
+type Session struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	DeviceName string
+	UserAgent  string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
 type User struct {
@@ -95,3 +183,13 @@ type User struct {
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
 }
+
+type UserWarning struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	IssuedBy   uuid.NullUUID
+	TargetType string
+	TargetID   uuid.UUID
+	Note       string
+	CreatedAt  time.Time
+}
@@ -0,0 +1,152 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: otp_cache.sql
+
+package database
+
+import (
+	"context"
+	"time"
+)
+
+const countOTPSendsSince = `-- name: CountOTPSendsSince :one
+select count(*) from otp_send_log where email = $1 and sent_at >= $2
+`
+
+type CountOTPSendsSinceParams struct {
+	Email  string
+	SentAt time.Time
+}
+
+func (q *Queries) CountOTPSendsSince(ctx context.Context, arg CountOTPSendsSinceParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOTPSendsSince, arg.Email, arg.SentAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteOTP = `-- name: DeleteOTP :execrows
+delete from otp_cache where verification_token = $1
+`
+
+func (q *Queries) DeleteOTP(ctx context.Context, verificationToken string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOTP, verificationToken)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOTPSendsBefore = `-- name: DeleteOTPSendsBefore :execrows
+delete from otp_send_log where sent_at < $1
+`
+
+func (q *Queries) DeleteOTPSendsBefore(ctx context.Context, sentAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOTPSendsBefore, sentAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteOTPsIssuedBefore = `-- name: DeleteOTPsIssuedBefore :execrows
+delete from otp_cache where issued_at < $1
+`
+
+func (q *Queries) DeleteOTPsIssuedBefore(ctx context.Context, issuedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteOTPsIssuedBefore, issuedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getOTP = `-- name: GetOTP :one
+select otp, email, purpose, issued_at, attempts from otp_cache where verification_token = $1
+`
+
+type GetOTPRow struct {
+	Otp      string
+	Email    string
+	Purpose  string
+	IssuedAt time.Time
+	Attempts int32
+}
+
+func (q *Queries) GetOTP(ctx context.Context, verificationToken string) (GetOTPRow, error) {
+	row := q.db.QueryRowContext(ctx, getOTP, verificationToken)
+	var i GetOTPRow
+	err := row.Scan(
+		&i.Otp,
+		&i.Email,
+		&i.Purpose,
+		&i.IssuedAt,
+		&i.Attempts,
+	)
+	return i, err
+}
+
+const incrementOTPAttempts = `-- name: IncrementOTPAttempts :one
+update otp_cache set attempts = attempts + 1, updated_at = NOW() where verification_token = $1
+returning attempts
+`
+
+func (q *Queries) IncrementOTPAttempts(ctx context.Context, verificationToken string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, incrementOTPAttempts, verificationToken)
+	var attempts int32
+	err := row.Scan(&attempts)
+	return attempts, err
+}
+
+const recordOTPSend = `-- name: RecordOTPSend :exec
+insert into otp_send_log(email, sent_at) values($1, $2)
+`
+
+type RecordOTPSendParams struct {
+	Email  string
+	SentAt time.Time
+}
+
+func (q *Queries) RecordOTPSend(ctx context.Context, arg RecordOTPSendParams) error {
+	_, err := q.db.ExecContext(ctx, recordOTPSend, arg.Email, arg.SentAt)
+	return err
+}
+
+const setOTP = `-- name: SetOTP :exec
+insert into otp_cache(verification_token, otp, email, purpose, issued_at, attempts, created_at, updated_at)
+values(
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    NOW()
+)
+on conflict(verification_token) do update
+set otp = excluded.otp, email = excluded.email, purpose = excluded.purpose,
+issued_at = excluded.issued_at, attempts = excluded.attempts, updated_at = NOW()
+`
+
+type SetOTPParams struct {
+	VerificationToken string
+	Otp               string
+	Email             string
+	Purpose           string
+	IssuedAt          time.Time
+	Attempts          int32
+}
+
+func (q *Queries) SetOTP(ctx context.Context, arg SetOTPParams) error {
+	_, err := q.db.ExecContext(ctx, setOTP,
+		arg.VerificationToken,
+		arg.Otp,
+		arg.Email,
+		arg.Purpose,
+		arg.IssuedAt,
+		arg.Attempts,
+	)
+	return err
+}
@@ -7,42 +7,161 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :exec
-insert into refresh_token(token, user_id, expires_at, created_at, updated_at)
+insert into refresh_token(token_hash, session_id, user_id, expires_at, created_at, updated_at)
 values(
     $1,
     $2,
     $3,
+    $4,
     NOW(),
     NOW()
 )
 `
 
 type CreateRefreshTokenParams struct {
-	Token     string
+	TokenHash string
+	SessionID uuid.UUID
 	UserID    uuid.UUID
 	ExpiresAt time.Time
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error {
-	_, err := q.db.ExecContext(ctx, createRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	_, err := q.db.ExecContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.SessionID,
+		arg.UserID,
+		arg.ExpiresAt,
+	)
 	return err
 }
 
-const getRefreshTokenExpirationTime = `-- name: GetRefreshTokenExpirationTime :one
-select expires_at from refresh_token where user_id = $1
+const createSession = `-- name: CreateSession :one
+insert into sessions(
+    id, user_id, device_name, user_agent,
+    last_used_at, expires_at, created_at, updated_at
+) values(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW(),
+    $4,
+    NOW(),
+    NOW()
+)
+returning id
+`
+
+type CreateSessionParams struct {
+	UserID     uuid.UUID
+	DeviceName string
+	UserAgent  string
+	ExpiresAt  time.Time
+}
+
+func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, createSession,
+		arg.UserID,
+		arg.DeviceName,
+		arg.UserAgent,
+		arg.ExpiresAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getActiveSessionsByUserID = `-- name: GetActiveSessionsByUserID :many
+select id, device_name, user_agent, last_used_at, expires_at, created_at from sessions
+where user_id = $1 and revoked_at is null and expires_at > $2
+order by last_used_at desc
+`
+
+type GetActiveSessionsByUserIDParams struct {
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+type GetActiveSessionsByUserIDRow struct {
+	ID         uuid.UUID
+	DeviceName string
+	UserAgent  string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetActiveSessionsByUserID(ctx context.Context, arg GetActiveSessionsByUserIDParams) ([]GetActiveSessionsByUserIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessionsByUserID, arg.UserID, arg.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSessionsByUserIDRow
+	for rows.Next() {
+		var i GetActiveSessionsByUserIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.DeviceName,
+			&i.UserAgent,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenSession = `-- name: GetRefreshTokenSession :one
+select session_id from refresh_token where token_hash = $1
+`
+
+func (q *Queries) GetRefreshTokenSession(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenSession, tokenHash)
+	var session_id uuid.UUID
+	err := row.Scan(&session_id)
+	return session_id, err
+}
+
+const getSessionByID = `-- name: GetSessionByID :one
+select sessions.user_id, roles.role_name, sessions.expires_at, sessions.revoked_at
+from sessions join users on sessions.user_id = users.id join roles on users.role_id = roles.id
+where sessions.id = $1
 `
 
-func (q *Queries) GetRefreshTokenExpirationTime(ctx context.Context, userID uuid.UUID) (time.Time, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshTokenExpirationTime, userID)
-	var expires_at time.Time
-	err := row.Scan(&expires_at)
-	return expires_at, err
+type GetSessionByIDRow struct {
+	UserID    uuid.UUID
+	RoleName  string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+}
+
+func (q *Queries) GetSessionByID(ctx context.Context, id uuid.UUID) (GetSessionByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getSessionByID, id)
+	var i GetSessionByIDRow
+	err := row.Scan(
+		&i.UserID,
+		&i.RoleName,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+	)
+	return i, err
 }
 
 const removeRefreshToken = `-- name: RemoveRefreshToken :exec
@@ -53,3 +172,70 @@ func (q *Queries) RemoveRefreshToken(ctx context.Context, userID uuid.UUID) erro
 	_, err := q.db.ExecContext(ctx, removeRefreshToken, userID)
 	return err
 }
+
+const revokeAllSessions = `-- name: RevokeAllSessions :exec
+update sessions set revoked_at = NOW(), updated_at = NOW() where user_id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeAllSessions(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllSessions, userID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :exec
+update sessions set revoked_at = NOW(), updated_at = NOW() where id = $1 and revoked_at is null
+`
+
+func (q *Queries) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeSession, id)
+	return err
+}
+
+const revokeUserSession = `-- name: RevokeUserSession :execrows
+update sessions set revoked_at = NOW(), updated_at = NOW() where id = $1 and user_id = $2 and revoked_at is null
+`
+
+type RevokeUserSessionParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RevokeUserSession(ctx context.Context, arg RevokeUserSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserSession, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const touchSession = `-- name: TouchSession :exec
+update sessions set last_used_at = NOW(), expires_at = $1, updated_at = NOW() where id = $2
+`
+
+type TouchSessionParams struct {
+	ExpiresAt time.Time
+	ID        uuid.UUID
+}
+
+func (q *Queries) TouchSession(ctx context.Context, arg TouchSessionParams) error {
+	_, err := q.db.ExecContext(ctx, touchSession, arg.ExpiresAt, arg.ID)
+	return err
+}
+
+const useRefreshToken = `-- name: UseRefreshToken :one
+update refresh_token set used_at = NOW(), updated_at = NOW() where token_hash = $1 and used_at is null
+returning session_id, user_id, expires_at
+`
+
+type UseRefreshTokenRow struct {
+	SessionID uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) UseRefreshToken(ctx context.Context, tokenHash string) (UseRefreshTokenRow, error) {
+	row := q.db.QueryRowContext(ctx, useRefreshToken, tokenHash)
+	var i UseRefreshTokenRow
+	err := row.Scan(&i.SessionID, &i.UserID, &i.ExpiresAt)
+	return i, err
+}
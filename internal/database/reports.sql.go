@@ -0,0 +1,230 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: reports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const countOpenReports = `-- name: CountOpenReports :one
+select count(*) from reports where target_type = $1 and target_id = $2 and status = 'open'
+`
+
+type CountOpenReportsParams struct {
+	TargetType string
+	TargetID   uuid.UUID
+}
+
+func (q *Queries) CountOpenReports(ctx context.Context, arg CountOpenReportsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countOpenReports, arg.TargetType, arg.TargetID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createReport = `-- name: CreateReport :one
+insert into reports(
+    id, reporter_id, target_type, target_id,
+    reason, details, created_at
+) values(
+    gen_random_uuid(),
+    $1, $2, $3, $4, $5, NOW()
+)
+on conflict (reporter_id, target_type, target_id) do nothing
+returning id, created_at
+`
+
+type CreateReportParams struct {
+	ReporterID uuid.UUID
+	TargetType string
+	TargetID   uuid.UUID
+	Reason     string
+	Details    string
+}
+
+type CreateReportRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+}
+
+func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (CreateReportRow, error) {
+	row := q.db.QueryRowContext(ctx, createReport,
+		arg.ReporterID,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Reason,
+		arg.Details,
+	)
+	var i CreateReportRow
+	err := row.Scan(&i.ID, &i.CreatedAt)
+	return i, err
+}
+
+const createUserWarning = `-- name: CreateUserWarning :exec
+insert into user_warnings(
+    id, user_id, issued_by, target_type,
+    target_id, note, created_at
+) values(
+    gen_random_uuid(),
+    $1, $2, $3, $4, $5, NOW()
+)
+`
+
+type CreateUserWarningParams struct {
+	UserID     uuid.UUID
+	IssuedBy   uuid.NullUUID
+	TargetType string
+	TargetID   uuid.UUID
+	Note       string
+}
+
+func (q *Queries) CreateUserWarning(ctx context.Context, arg CreateUserWarningParams) error {
+	_, err := q.db.ExecContext(ctx, createUserWarning,
+		arg.UserID,
+		arg.IssuedBy,
+		arg.TargetType,
+		arg.TargetID,
+		arg.Note,
+	)
+	return err
+}
+
+const getOpenReportsByTarget = `-- name: GetOpenReportsByTarget :many
+select reports.id, users.username as reporter, reports.reason, reports.details, reports.created_at
+from reports join users on reports.reporter_id = users.id
+where reports.target_type = $1 and reports.target_id = $2 and reports.status = 'open'
+order by reports.created_at, reports.id
+`
+
+type GetOpenReportsByTargetParams struct {
+	TargetType string
+	TargetID   uuid.UUID
+}
+
+type GetOpenReportsByTargetRow struct {
+	ID        uuid.UUID
+	Reporter  string
+	Reason    string
+	Details   string
+	CreatedAt time.Time
+}
+
+func (q *Queries) GetOpenReportsByTarget(ctx context.Context, arg GetOpenReportsByTargetParams) ([]GetOpenReportsByTargetRow, error) {
+	rows, err := q.db.QueryContext(ctx, getOpenReportsByTarget, arg.TargetType, arg.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOpenReportsByTargetRow
+	for rows.Next() {
+		var i GetOpenReportsByTargetRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Reporter,
+			&i.Reason,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReportedTargets = `-- name: GetReportedTargets :many
+select target_type, target_id, count(*) as open_reports,
+array_agg(distinct reason)::text[] as reasons,
+max(created_at)::timestamp as last_reported_at
+from reports
+where status = 'open'
+group by target_type, target_id
+having $1::bigint is null or (count(*), target_id) < ($1::bigint, $2::uuid)
+order by open_reports desc, target_id desc
+limit $3
+`
+
+type GetReportedTargetsParams struct {
+	AfterOpenReports sql.NullInt64
+	AfterID          uuid.NullUUID
+	PageLimit        int32
+}
+
+type GetReportedTargetsRow struct {
+	TargetType     string
+	TargetID       uuid.UUID
+	OpenReports    int64
+	Reasons        []string
+	LastReportedAt time.Time
+}
+
+func (q *Queries) GetReportedTargets(ctx context.Context, arg GetReportedTargetsParams) ([]GetReportedTargetsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReportedTargets, arg.AfterOpenReports, arg.AfterID, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReportedTargetsRow
+	for rows.Next() {
+		var i GetReportedTargetsRow
+		if err := rows.Scan(
+			&i.TargetType,
+			&i.TargetID,
+			&i.OpenReports,
+			pq.Array(&i.Reasons),
+			&i.LastReportedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resolveReports = `-- name: ResolveReports :execrows
+update reports set status = $1, resolution = $2,
+resolved_by = $3, resolved_at = NOW()
+where target_type = $4 and target_id = $5 and status = 'open'
+`
+
+type ResolveReportsParams struct {
+	Status     string
+	Resolution sql.NullString
+	ResolvedBy uuid.NullUUID
+	TargetType string
+	TargetID   uuid.UUID
+}
+
+func (q *Queries) ResolveReports(ctx context.Context, arg ResolveReportsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resolveReports,
+		arg.Status,
+		arg.Resolution,
+		arg.ResolvedBy,
+		arg.TargetType,
+		arg.TargetID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
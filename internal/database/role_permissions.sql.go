@@ -0,0 +1,107 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.28.0
+// source: role_permissions.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const getPermissionsByRoleID = `-- name: GetPermissionsByRoleID :many
+select permission, created_at from role_permissions where role_id = $1 order by permission
+`
+
+type GetPermissionsByRoleIDRow struct {
+	Permission string
+	CreatedAt  time.Time
+}
+
+func (q *Queries) GetPermissionsByRoleID(ctx context.Context, roleID uuid.UUID) ([]GetPermissionsByRoleIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPermissionsByRoleID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPermissionsByRoleIDRow
+	for rows.Next() {
+		var i GetPermissionsByRoleIDRow
+		if err := rows.Scan(&i.Permission, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPermissionsByRoleName = `-- name: GetPermissionsByRoleName :many
+select role_permissions.permission from role_permissions
+join roles on role_permissions.role_id = roles.id
+where roles.role_name = $1
+`
+
+func (q *Queries) GetPermissionsByRoleName(ctx context.Context, roleName string) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, getPermissionsByRoleName, roleName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, err
+		}
+		items = append(items, permission)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const grantPermission = `-- name: GrantPermission :exec
+insert into role_permissions(role_id, permission, created_at)
+values($1, $2, NOW())
+on conflict(role_id, permission) do nothing
+`
+
+type GrantPermissionParams struct {
+	RoleID     uuid.UUID
+	Permission string
+}
+
+func (q *Queries) GrantPermission(ctx context.Context, arg GrantPermissionParams) error {
+	_, err := q.db.ExecContext(ctx, grantPermission, arg.RoleID, arg.Permission)
+	return err
+}
+
+const revokePermission = `-- name: RevokePermission :execrows
+delete from role_permissions where role_id = $1 and permission = $2
+`
+
+type RevokePermissionParams struct {
+	RoleID     uuid.UUID
+	Permission string
+}
+
+func (q *Queries) RevokePermission(ctx context.Context, arg RevokePermissionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokePermission, arg.RoleID, arg.Permission)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
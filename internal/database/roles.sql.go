@@ -12,6 +12,23 @@ import (
 	"github.com/google/uuid"
 )
 
+const assignUserRole = `-- name: AssignUserRole :execrows
+update users set role_id = $1, updated_at = NOW() where id = $2
+`
+
+type AssignUserRoleParams struct {
+	RoleID uuid.UUID
+	ID     uuid.UUID
+}
+
+func (q *Queries) AssignUserRole(ctx context.Context, arg AssignUserRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, assignUserRole, arg.RoleID, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createRole = `-- name: CreateRole :one
 insert into roles(id, role_name, created_at, updated_at)
 values(
@@ -20,7 +37,7 @@ values(
     NOW(),
     NOW()
 )
-returning id, role_name, created_at, updated_at
+returning id, role_name, created_at, updated_at, is_builtin
 `
 
 func (q *Queries) CreateRole(ctx context.Context, roleName string) (Role, error) {
@@ -31,16 +48,51 @@ func (q *Queries) CreateRole(ctx context.Context, roleName string) (Role, error)
 		&i.RoleName,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsBuiltin,
 	)
 	return i, err
 }
 
+const createRoleAuditLog = `-- name: CreateRoleAuditLog :exec
+insert into role_audit_log(id, actor_id, action, role_id, target_user_id, details, created_at)
+values(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+`
+
+type CreateRoleAuditLogParams struct {
+	ActorID      uuid.NullUUID
+	Action       string
+	RoleID       uuid.UUID
+	TargetUserID uuid.NullUUID
+	Details      string
+}
+
+func (q *Queries) CreateRoleAuditLog(ctx context.Context, arg CreateRoleAuditLogParams) error {
+	_, err := q.db.ExecContext(ctx, createRoleAuditLog,
+		arg.ActorID,
+		arg.Action,
+		arg.RoleID,
+		arg.TargetUserID,
+		arg.Details,
+	)
+	return err
+}
+
 const getAllRoles = `-- name: GetAllRoles :many
-select role_name, created_at, updated_at from roles
+select id, role_name, is_builtin, created_at, updated_at from roles order by role_name
 `
 
 type GetAllRolesRow struct {
+	ID        uuid.UUID
 	RoleName  string
+	IsBuiltin bool
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -54,7 +106,50 @@ func (q *Queries) GetAllRoles(ctx context.Context) ([]GetAllRolesRow, error) {
 	var items []GetAllRolesRow
 	for rows.Next() {
 		var i GetAllRolesRow
-		if err := rows.Scan(&i.RoleName, &i.CreatedAt, &i.UpdatedAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.RoleName,
+			&i.IsBuiltin,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRoleAuditLog = `-- name: GetRoleAuditLog :many
+select role_audit_log.id, role_audit_log.actor_id, role_audit_log.action, role_audit_log.role_id,
+role_audit_log.target_user_id, role_audit_log.details, role_audit_log.created_at
+from role_audit_log order by created_at desc, id desc limit $1
+`
+
+func (q *Queries) GetRoleAuditLog(ctx context.Context, limit int32) ([]RoleAuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, getRoleAuditLog, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []RoleAuditLog
+	for rows.Next() {
+		var i RoleAuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.ActorID,
+			&i.Action,
+			&i.RoleID,
+			&i.TargetUserID,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -69,14 +164,19 @@ func (q *Queries) GetAllRoles(ctx context.Context) ([]GetAllRolesRow, error) {
 }
 
 const getRoleById = `-- name: GetRoleById :one
-select role_name from roles where id = $1
+select role_name, is_builtin from roles where id = $1
 `
 
-func (q *Queries) GetRoleById(ctx context.Context, id uuid.UUID) (string, error) {
+type GetRoleByIdRow struct {
+	RoleName  string
+	IsBuiltin bool
+}
+
+func (q *Queries) GetRoleById(ctx context.Context, id uuid.UUID) (GetRoleByIdRow, error) {
 	row := q.db.QueryRowContext(ctx, getRoleById, id)
-	var role_name string
-	err := row.Scan(&role_name)
-	return role_name, err
+	var i GetRoleByIdRow
+	err := row.Scan(&i.RoleName, &i.IsBuiltin)
+	return i, err
 }
 
 const getRoleIdByName = `-- name: GetRoleIdByName :one
@@ -90,17 +190,34 @@ func (q *Queries) GetRoleIdByName(ctx context.Context, roleName string) (uuid.UU
 	return id, err
 }
 
-const removeRole = `-- name: RemoveRole :exec
-delete from roles where id = $1
+const isLastAdmin = `-- name: IsLastAdmin :one
+select (
+    exists(select 1 from users join roles on users.role_id = roles.id where users.id = $1 and roles.role_name = 'admin')
+    and (select count(*) from users join roles on users.role_id = roles.id where roles.role_name = 'admin') = 1
+)::boolean
 `
 
-func (q *Queries) RemoveRole(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, removeRole, id)
-	return err
+func (q *Queries) IsLastAdmin(ctx context.Context, id uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isLastAdmin, id)
+	var column_1 bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const removeRole = `-- name: RemoveRole :execrows
+delete from roles where id = $1 and is_builtin = false
+`
+
+func (q *Queries) RemoveRole(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeRole, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 const updateRoleById = `-- name: UpdateRoleById :one
-update roles set role_name = $1, updated_at = NOW() where id = $2
+update roles set role_name = $1, updated_at = NOW() where id = $2 and is_builtin = false
 returning role_name, created_at, updated_at
 `
 
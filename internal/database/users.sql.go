@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -122,35 +123,57 @@ func (q *Queries) RemoveUser(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const searchBlogsByTags = `-- name: SearchBlogsByTags :many
-select id, title, brief, thumbnail_url, views from blogs
-where tags && $1
+const searchBlogFacets = `-- name: SearchBlogFacets :many
+with matches as (
+    select category, tags, created_at from blogs
+    where not hidden
+    and (search_vector @@ websearch_to_tsquery('english', $1::text) or title % $1::text)
+    and ($2::text is null or category = (select id from categories where categories.category = $2::text))
+    and ($3::text[] is null or tags @> $3::text[])
+    and ($4::int is null or extract(year from created_at)::int = $4::int)
+)
+select 'category'::text as facet, categories.category::text as value, count(*) as count
+from matches join categories on matches.category = categories.id
+group by categories.category
+union all
+select 'tag'::text as facet, tag::text as value, count(*) as count
+from matches cross join unnest(matches.tags) as tag
+group by tag
+union all
+select 'year'::text as facet, extract(year from created_at)::int::text as value, count(*) as count
+from matches
+group by extract(year from created_at)::int
+order by facet, count desc, value
 `
 
-type SearchBlogsByTagsRow struct {
-	ID           uuid.UUID
-	Title        string
-	Brief        string
-	ThumbnailUrl string
-	Views        int32
+type SearchBlogFacetsParams struct {
+	Query    string
+	Category sql.NullString
+	Tags     []string
+	Year     sql.NullInt32
+}
+
+type SearchBlogFacetsRow struct {
+	Facet string
+	Value string
+	Count int64
 }
 
-func (q *Queries) SearchBlogsByTags(ctx context.Context, tags []string) ([]SearchBlogsByTagsRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchBlogsByTags, pq.Array(tags))
+func (q *Queries) SearchBlogFacets(ctx context.Context, arg SearchBlogFacetsParams) ([]SearchBlogFacetsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchBlogFacets,
+		arg.Query,
+		arg.Category,
+		pq.Array(arg.Tags),
+		arg.Year,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchBlogsByTagsRow
+	var items []SearchBlogFacetsRow
 	for rows.Next() {
-		var i SearchBlogsByTagsRow
-		if err := rows.Scan(
-			&i.ID,
-			&i.Title,
-			&i.Brief,
-			&i.ThumbnailUrl,
-			&i.Views,
-		); err != nil {
+		var i SearchBlogFacetsRow
+		if err := rows.Scan(&i.Facet, &i.Value, &i.Count); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -164,40 +187,72 @@ func (q *Queries) SearchBlogsByTags(ctx context.Context, tags []string) ([]Searc
 	return items, nil
 }
 
-const searchBlogsByTitle = `-- name: SearchBlogsByTitle :many
-select id, title, brief, thumbnail_url, views from blogs
-where title ilike $1 or title ilike $2 or title ilike $3
+const searchBlogs = `-- name: SearchBlogs :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+    ts_headline('english', blogs.title, websearch_to_tsquery('english', $1::text), 'HighlightAll=true')::text as title_highlight,
+    ts_headline('english', blogs.brief, websearch_to_tsquery('english', $1::text), 'MaxFragments=2, MaxWords=20, MinWords=5')::text as snippet,
+    ranked.rank
+from (
+    select id, (ts_rank(search_vector, websearch_to_tsquery('english', $1::text)) + similarity(title, $1::text))::real as rank
+    from blogs
+    where not hidden
+    and (search_vector @@ websearch_to_tsquery('english', $1::text) or title % $1::text)
+    and ($2::text is null or category = (select id from categories where categories.category = $2::text))
+    and ($3::text[] is null or tags @> $3::text[])
+    and ($4::int is null or extract(year from created_at)::int = $4::int)
+) as ranked join blogs on blogs.id = ranked.id
+where ($5::real is null or (ranked.rank, ranked.id) < ($5::real, $6::uuid))
+order by ranked.rank desc, ranked.id desc
+limit $7
 `
 
-type SearchBlogsByTitleParams struct {
-	Title   string
-	Title_2 string
-	Title_3 string
+type SearchBlogsParams struct {
+	Query     string
+	Category  sql.NullString
+	Tags      []string
+	Year      sql.NullInt32
+	AfterRank sql.NullFloat64
+	AfterID   uuid.NullUUID
+	PageLimit int32
 }
 
-type SearchBlogsByTitleRow struct {
-	ID           uuid.UUID
-	Title        string
-	Brief        string
-	ThumbnailUrl string
-	Views        int32
+type SearchBlogsRow struct {
+	ID             uuid.UUID
+	Title          string
+	Brief          string
+	ThumbnailUrl   string
+	Views          int32
+	TitleHighlight string
+	Snippet        string
+	Rank           float32
 }
 
-func (q *Queries) SearchBlogsByTitle(ctx context.Context, arg SearchBlogsByTitleParams) ([]SearchBlogsByTitleRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchBlogsByTitle, arg.Title, arg.Title_2, arg.Title_3)
+func (q *Queries) SearchBlogs(ctx context.Context, arg SearchBlogsParams) ([]SearchBlogsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchBlogs,
+		arg.Query,
+		arg.Category,
+		pq.Array(arg.Tags),
+		arg.Year,
+		arg.AfterRank,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchBlogsByTitleRow
+	var items []SearchBlogsRow
 	for rows.Next() {
-		var i SearchBlogsByTitleRow
+		var i SearchBlogsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.Title,
 			&i.Brief,
 			&i.ThumbnailUrl,
 			&i.Views,
+			&i.TitleHighlight,
+			&i.Snippet,
+			&i.Rank,
 		); err != nil {
 			return nil, err
 		}
@@ -212,27 +267,56 @@ func (q *Queries) SearchBlogsByTitle(ctx context.Context, arg SearchBlogsByTitle
 	return items, nil
 }
 
-const searchBooksByTags = `-- name: SearchBooksByTags :many
-select id, name, cover_image_url from books
-where tags && $1
+const searchBookFacets = `-- name: SearchBookFacets :many
+with matches as (
+    select level, tags, created_at from books
+    where (search_vector @@ websearch_to_tsquery('english', $1::text) or name % $1::text)
+    and ($2::text is null or level = (select id from book_level where book_level.level = $2::text))
+    and ($3::text[] is null or tags @> $3::text[])
+    and ($4::int is null or extract(year from created_at)::int = $4::int)
+)
+select 'level'::text as facet, book_level.level::text as value, count(*) as count
+from matches join book_level on matches.level = book_level.id
+group by book_level.level
+union all
+select 'tag'::text as facet, tag::text as value, count(*) as count
+from matches cross join unnest(matches.tags) as tag
+group by tag
+union all
+select 'year'::text as facet, extract(year from created_at)::int::text as value, count(*) as count
+from matches
+group by extract(year from created_at)::int
+order by facet, count desc, value
 `
 
-type SearchBooksByTagsRow struct {
-	ID            uuid.UUID
-	Name          string
-	CoverImageUrl string
+type SearchBookFacetsParams struct {
+	Query string
+	Level sql.NullString
+	Tags  []string
+	Year  sql.NullInt32
+}
+
+type SearchBookFacetsRow struct {
+	Facet string
+	Value string
+	Count int64
 }
 
-func (q *Queries) SearchBooksByTags(ctx context.Context, tags []string) ([]SearchBooksByTagsRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchBooksByTags, pq.Array(tags))
+func (q *Queries) SearchBookFacets(ctx context.Context, arg SearchBookFacetsParams) ([]SearchBookFacetsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchBookFacets,
+		arg.Query,
+		arg.Level,
+		pq.Array(arg.Tags),
+		arg.Year,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchBooksByTagsRow
+	var items []SearchBookFacetsRow
 	for rows.Next() {
-		var i SearchBooksByTagsRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.CoverImageUrl); err != nil {
+		var i SearchBookFacetsRow
+		if err := rows.Scan(&i.Facet, &i.Value, &i.Count); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -246,33 +330,68 @@ func (q *Queries) SearchBooksByTags(ctx context.Context, tags []string) ([]Searc
 	return items, nil
 }
 
-const searchBooksByTitle = `-- name: SearchBooksByTitle :many
-select id, name, cover_image_url from books
-where name ilike $1 and name ilike $2 and name ilike $3
+const searchBooks = `-- name: SearchBooks :many
+select books.id, books.name, books.cover_image_url,
+    ts_headline('english', books.name, websearch_to_tsquery('english', $1::text), 'HighlightAll=true')::text as name_highlight,
+    ts_headline('english', books.review, websearch_to_tsquery('english', $1::text), 'MaxFragments=2, MaxWords=20, MinWords=5')::text as snippet,
+    ranked.rank
+from (
+    select id, (ts_rank(search_vector, websearch_to_tsquery('english', $1::text)) + similarity(name, $1::text))::real as rank
+    from books
+    where (search_vector @@ websearch_to_tsquery('english', $1::text) or name % $1::text)
+    and ($2::text is null or level = (select id from book_level where book_level.level = $2::text))
+    and ($3::text[] is null or tags @> $3::text[])
+    and ($4::int is null or extract(year from created_at)::int = $4::int)
+) as ranked join books on books.id = ranked.id
+where ($5::real is null or (ranked.rank, ranked.id) < ($5::real, $6::uuid))
+order by ranked.rank desc, ranked.id desc
+limit $7
 `
 
-type SearchBooksByTitleParams struct {
-	Name   string
-	Name_2 string
-	Name_3 string
+type SearchBooksParams struct {
+	Query     string
+	Level     sql.NullString
+	Tags      []string
+	Year      sql.NullInt32
+	AfterRank sql.NullFloat64
+	AfterID   uuid.NullUUID
+	PageLimit int32
 }
 
-type SearchBooksByTitleRow struct {
+type SearchBooksRow struct {
 	ID            uuid.UUID
 	Name          string
 	CoverImageUrl string
+	NameHighlight string
+	Snippet       string
+	Rank          float32
 }
 
-func (q *Queries) SearchBooksByTitle(ctx context.Context, arg SearchBooksByTitleParams) ([]SearchBooksByTitleRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchBooksByTitle, arg.Name, arg.Name_2, arg.Name_3)
+func (q *Queries) SearchBooks(ctx context.Context, arg SearchBooksParams) ([]SearchBooksRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchBooks,
+		arg.Query,
+		arg.Level,
+		pq.Array(arg.Tags),
+		arg.Year,
+		arg.AfterRank,
+		arg.AfterID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []SearchBooksByTitleRow
+	var items []SearchBooksRow
 	for rows.Next() {
-		var i SearchBooksByTitleRow
-		if err := rows.Scan(&i.ID, &i.Name, &i.CoverImageUrl); err != nil {
+		var i SearchBooksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CoverImageUrl,
+			&i.NameHighlight,
+			&i.Snippet,
+			&i.Rank,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -286,6 +405,150 @@ func (q *Queries) SearchBooksByTitle(ctx context.Context, arg SearchBooksByTitle
 	return items, nil
 }
 
+const suggestBlogTitles = `-- name: SuggestBlogTitles :many
+select title from blogs
+where not hidden and (title ilike escape_like($1::text) || '%' escape '\' or $1::text <% title)
+order by word_similarity($1::text, title) desc, title
+limit $2
+`
+
+type SuggestBlogTitlesParams struct {
+	Query     string
+	PageLimit int32
+}
+
+func (q *Queries) SuggestBlogTitles(ctx context.Context, arg SuggestBlogTitlesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, suggestBlogTitles, arg.Query, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		items = append(items, title)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const suggestBookNames = `-- name: SuggestBookNames :many
+select name from books
+where name ilike escape_like($1::text) || '%' escape '\' or $1::text <% name
+order by word_similarity($1::text, name) desc, name
+limit $2
+`
+
+type SuggestBookNamesParams struct {
+	Query     string
+	PageLimit int32
+}
+
+func (q *Queries) SuggestBookNames(ctx context.Context, arg SuggestBookNamesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, suggestBookNames, arg.Query, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const suggestCategories = `-- name: SuggestCategories :many
+select category from categories
+where category ilike escape_like($1::text) || '%' escape '\' or $1::text <% category
+order by word_similarity($1::text, category) desc, category
+limit $2
+`
+
+type SuggestCategoriesParams struct {
+	Query     string
+	PageLimit int32
+}
+
+func (q *Queries) SuggestCategories(ctx context.Context, arg SuggestCategoriesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, suggestCategories, arg.Query, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, err
+		}
+		items = append(items, category)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const suggestTags = `-- name: SuggestTags :many
+select tag::text from (
+    select unnest(tags) as tag from blogs where not hidden
+    union
+    select unnest(tags) as tag from books
+) as all_tags
+where tag ilike escape_like($1::text) || '%' escape '\' or $1::text <% tag
+order by word_similarity($1::text, tag) desc, tag
+limit $2
+`
+
+type SuggestTagsParams struct {
+	Query     string
+	PageLimit int32
+}
+
+func (q *Queries) SuggestTags(ctx context.Context, arg SuggestTagsParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, suggestTags, arg.Query, arg.PageLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		items = append(items, tag)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateEmail = `-- name: UpdateEmail :exec
 update users set email = $1, updated_at = NOW() where id = $2
 `
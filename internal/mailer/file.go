@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// mailer writing every message as an .eml file into a directory,
+// useful for local development where no smtp server is available
+type fileMailer struct {
+	directory string
+}
+
+func NewFileMailer(directory string) (Mailer, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &fileMailer{
+		directory: directory,
+	}, nil
+}
+
+func (mailer *fileMailer) Send(ctx context.Context, message Message) error {
+	// generating a unique file name for the message
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	fileName := time.Now().UTC().Format("20060102T150405.000000000") + "-" + hex.EncodeToString(suffix) + ".eml"
+
+	return os.WriteFile(filepath.Join(mailer.directory, fileName), message.Bytes(), 0o644)
+}
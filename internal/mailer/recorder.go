@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder is an in memory mailer which keeps every sent message, meant for tests
+type Recorder struct {
+	messages []Message
+	lock     sync.Mutex
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (recorder *Recorder) Send(ctx context.Context, message Message) error {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.messages = append(recorder.messages, message)
+	return nil
+}
+
+// Messages returns a copy of all the recorded messages in the order they were sent
+func (recorder *Recorder) Messages() []Message {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	messages := make([]Message, len(recorder.messages))
+	copy(messages, recorder.messages)
+	return messages
+}
+
+// Reset removes all the recorded messages
+func (recorder *Recorder) Reset() {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	recorder.messages = nil
+}
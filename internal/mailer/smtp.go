@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"context"
+	"log"
+	"net/smtp"
+)
+
+// mailer sending messages through an smtp server using plain auth
+type smtpMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(host string, port string, username string, password string) Mailer {
+	return &smtpMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+	}
+}
+
+func (mailer *smtpMailer) Send(ctx context.Context, message Message) error {
+	// authentication
+	auth := smtp.PlainAuth("", mailer.username, mailer.password, mailer.host)
+
+	// sending the mail
+	if err := smtp.SendMail(mailer.host+":"+mailer.port, auth, message.From, message.To, message.Bytes()); err != nil {
+		log.Println("Error Sending Mail: ", err)
+		return err
+	}
+
+	return nil
+}
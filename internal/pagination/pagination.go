@@ -0,0 +1,144 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 100")
+)
+
+// position of the last row of a page, the sort key orders the rows
+// and the id breaks the ties between rows having the same key
+type cursor[K any] struct {
+	Key K         `json:"k"`
+	ID  uuid.UUID `json:"id"`
+}
+
+// Page is the requested page of a list endpoint ordered by a sort key of type K
+type Page[K any] struct {
+	Limit int32
+
+	// position of the last row of the previous page, nil for the first page
+	After *cursor[K]
+}
+
+// Encode returns the opaque cursor pointing right after the row with the sort key and id
+func Encode[K any](key K, id uuid.UUID) string {
+	data, _ := json.Marshal(cursor[K]{
+		Key: key,
+		ID:  id,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Decode returns the sort key and id the cursor points after
+func Decode[K any](encoded string) (K, uuid.UUID, error) {
+	var decoded cursor[K]
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return decoded.Key, uuid.Nil, ErrInvalidCursor
+	}
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.ID == uuid.Nil {
+		return decoded.Key, uuid.Nil, ErrInvalidCursor
+	}
+
+	return decoded.Key, decoded.ID, nil
+}
+
+// LimitFromRequest reads the "limit" query parameter of endpoints returning a single
+// ranked page, it defaults to DefaultLimit and is rejected when it is above MaxLimit
+func LimitFromRequest(r *http.Request) (int32, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return DefaultLimit, nil
+	}
+
+	limit, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || limit <= 0 || limit > MaxLimit {
+		return DefaultLimit, ErrInvalidLimit
+	}
+
+	return int32(limit), nil
+}
+
+// FromRequest reads the "cursor" and "limit" query parameters, the limit defaults
+// to DefaultLimit and is rejected when it is above MaxLimit
+func FromRequest[K any](r *http.Request) (Page[K], error) {
+	return FromRequestParam[K](r, "cursor")
+}
+
+// FromRequestParam is FromRequest for endpoints returning several lists which
+// are paged independently, each list reads its cursor from its own parameter
+func FromRequestParam[K any](r *http.Request, cursorParam string) (Page[K], error) {
+	limit, err := LimitFromRequest(r)
+	page := Page[K]{
+		Limit: limit,
+	}
+	if err != nil {
+		return page, err
+	}
+
+	if value := r.URL.Query().Get(cursorParam); value != "" {
+		key, id, err := Decode[K](value)
+		if err != nil {
+			return page, err
+		}
+		page.After = &cursor[K]{
+			Key: key,
+			ID:  id,
+		}
+	}
+
+	return page, nil
+}
+
+// AfterKey returns the sort key of the previous page and whether there was one
+func (page Page[K]) AfterKey() (K, bool) {
+	if page.After == nil {
+		var key K
+		return key, false
+	}
+
+	return page.After.Key, true
+}
+
+// AfterID returns the id of the last row of the previous page, invalid for the first page
+func (page Page[K]) AfterID() uuid.NullUUID {
+	if page.After == nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: page.After.ID, Valid: true}
+}
+
+// FetchLimit is the number of rows to query, one more than the page size
+// so that the presence of a next page can be detected
+func (page Page[K]) FetchLimit() int32 {
+	return page.Limit + 1
+}
+
+// Trim cuts the rows fetched with FetchLimit down to the page size and returns the
+// cursor of the next page, which is empty when there are no more rows
+func Trim[T any, K any](page Page[K], rows []T, keyOf func(T) (K, uuid.UUID)) ([]T, string) {
+	if len(rows) <= int(page.Limit) {
+		return rows, ""
+	}
+
+	rows = rows[:page.Limit]
+	key, id := keyOf(rows[len(rows)-1])
+	return rows, Encode(key, id)
+}
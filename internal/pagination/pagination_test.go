@@ -0,0 +1,157 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	id := uuid.New()
+
+	t.Run("time", func(t *testing.T) {
+		key := time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)
+		decodedKey, decodedID, err := Decode[time.Time](Encode(key, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decodedKey.Equal(key) || decodedID != id {
+			t.Fatalf("got %v, %v, want %v, %v", decodedKey, decodedID, key, id)
+		}
+	})
+
+	t.Run("int64", func(t *testing.T) {
+		decodedKey, decodedID, err := Decode[int64](Encode(int64(42), id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decodedKey != 42 || decodedID != id {
+			t.Fatalf("got %v, %v", decodedKey, decodedID)
+		}
+	})
+
+	// search ranks are compared with the rows again so they must survive the round trip exactly
+	t.Run("float32", func(t *testing.T) {
+		key := float32(0.1) + float32(1)/3
+		decodedKey, _, err := Decode[float32](Encode(key, id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decodedKey != key {
+			t.Fatalf("got %v, want %v", decodedKey, key)
+		}
+	})
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"not_base64", "%%%"},
+		{"not_json", base64.RawURLEncoding.EncodeToString([]byte("cursor"))},
+		{"missing_id", base64.RawURLEncoding.EncodeToString([]byte(`{"k":1}`))},
+		{"wrong_key_type", Encode("newest", uuid.New())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Decode[int64](tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("got %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name          string
+		url           string
+		expectedLimit int32
+		expectedAfter bool
+		expectedErr   error
+	}{
+		{"defaults", "/", DefaultLimit, false, nil},
+		{"limit", "/?limit=5", 5, false, nil},
+		{"limit_above_max", "/?limit=101", DefaultLimit, false, ErrInvalidLimit},
+		{"zero_limit", "/?limit=0", DefaultLimit, false, ErrInvalidLimit},
+		{"cursor", "/?cursor=" + Encode(int64(7), id), DefaultLimit, true, nil},
+		{"invalid_cursor", "/?cursor=abc", DefaultLimit, false, ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := FromRequest[int64](httptest.NewRequest("GET", tt.url, nil))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got %v, want %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+			if page.Limit != tt.expectedLimit || (page.After != nil) != tt.expectedAfter {
+				t.Fatalf("got %+v", page)
+			}
+			if page.After != nil && (page.After.Key != 7 || page.AfterID() != uuid.NullUUID{UUID: id, Valid: true}) {
+				t.Fatalf("cursor decoded as %+v", *page.After)
+			}
+		})
+	}
+}
+
+func TestFromRequestParam(t *testing.T) {
+	id := uuid.New()
+	request := httptest.NewRequest("GET", "/?blogs_cursor="+Encode(float32(1.5), id), nil)
+
+	blogsPage, err := FromRequestParam[float32](request, "blogs_cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank, ok := blogsPage.AfterKey(); !ok || rank != 1.5 {
+		t.Fatalf("blogs cursor decoded as %v, %v", rank, ok)
+	}
+
+	booksPage, err := FromRequestParam[float32](request, "books_cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booksPage.After != nil {
+		t.Fatal("books page read the cursor of blogs")
+	}
+}
+
+func TestTrim(t *testing.T) {
+	type row struct {
+		key int64
+		id  uuid.UUID
+	}
+	keyOf := func(r row) (int64, uuid.UUID) {
+		return r.key, r.id
+	}
+	rows := []row{{3, uuid.New()}, {2, uuid.New()}, {1, uuid.New()}}
+
+	page := Page[int64]{Limit: 2}
+	if page.FetchLimit() != 3 {
+		t.Fatalf("fetch limit %d, want 3", page.FetchLimit())
+	}
+
+	trimmed, nextCursor := Trim(page, rows, keyOf)
+	if len(trimmed) != 2 {
+		t.Fatalf("%d rows, want 2", len(trimmed))
+	}
+	key, id, err := Decode[int64](nextCursor)
+	if err != nil || key != 2 || id != rows[1].id {
+		t.Fatalf("next cursor points after %v, %v, %v", key, id, err)
+	}
+
+	// the last page has no next cursor
+	trimmed, nextCursor = Trim(page, rows[:2], keyOf)
+	if len(trimmed) != 2 || nextCursor != "" {
+		t.Fatalf("last page returned %d rows and cursor %q", len(trimmed), nextCursor)
+	}
+}
@@ -0,0 +1,78 @@
+package permission
+
+import "slices"
+
+// permissions a role can be granted, every protected endpoint requires at least one of them
+const (
+	AccountManage = "account:manage"
+	SessionManage = "session:manage"
+
+	UserRead   = "user:read"
+	UserUnlock = "user:unlock"
+
+	BookCreate = "book:create"
+	BookUpdate = "book:update"
+	BookDelete = "book:delete"
+
+	CategoryCreate = "category:create"
+	CategoryUpdate = "category:update"
+	CategoryDelete = "category:delete"
+
+	BlogCreate = "blog:create"
+	BlogUpdate = "blog:update"
+	BlogDelete = "blog:delete"
+	BlogLike   = "blog:like"
+	BlogView   = "blog:view"
+
+	CommentCreate   = "comment:create"
+	CommentUpdate   = "comment:update"
+	CommentDelete   = "comment:delete"
+	CommentLike     = "comment:like"
+	CommentModerate = "comment:moderate"
+
+	ReportCreate = "report:create"
+	ReportManage = "report:manage"
+
+	PermissionManage = "permission:manage"
+	RoleManage       = "role:manage"
+
+	MetricsRead = "metrics:read"
+)
+
+var all = []string{
+	AccountManage,
+	SessionManage,
+	UserRead,
+	UserUnlock,
+	BookCreate,
+	BookUpdate,
+	BookDelete,
+	CategoryCreate,
+	CategoryUpdate,
+	CategoryDelete,
+	BlogCreate,
+	BlogUpdate,
+	BlogDelete,
+	BlogLike,
+	BlogView,
+	CommentCreate,
+	CommentUpdate,
+	CommentDelete,
+	CommentLike,
+	CommentModerate,
+	ReportCreate,
+	ReportManage,
+	PermissionManage,
+	RoleManage,
+	MetricsRead,
+}
+
+// All returns every known permission
+func All() []string {
+	return slices.Clone(all)
+}
+
+// IsValid reports whether the permission is a known one
+func IsValid(permission string) bool {
+	return slices.Contains(all, permission)
+}
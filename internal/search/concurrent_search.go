@@ -2,94 +2,248 @@ package search
 
 import (
 	"context"
+	"database/sql"
+	"sort"
 	"sync"
 
+	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/pagination"
 )
 
-func searchBlogs(tokens []string, ctx context.Context, db *database.Queries, blogsResultSet *safeSet[Blog], wg *sync.WaitGroup) {
-	// searching blogs by tags
-	if blogsSearchByTags, err := db.SearchBlogsByTags(ctx, tokens); err == nil && blogsSearchByTags != nil {
-		for _, value := range blogsSearchByTags {
-			blogsResultSet.Add(Blog{
-				ID:           value.ID,
-				Title:        value.Title,
-				Brief:        value.Brief,
-				ThumbnailUrl: value.ThumbnailUrl,
-				Views:        value.Views,
-			})
+// highlighted fragments of the title/name and snippet are wrapped in <b></b>
+type Blog struct {
+	ID             uuid.UUID
+	Title          string
+	Brief          string
+	ThumbnailUrl   string
+	Views          int32
+	TitleHighlight string
+	Snippet        string
+	Rank           float32
+}
+
+type Book struct {
+	ID            uuid.UUID
+	Name          string
+	CoverImageUrl string
+	NameHighlight string
+	Snippet       string
+	Rank          float32
+}
+
+// Filters are the facet values selected to refine the results, empty values are not
+// applied. The category only applies to blogs and the level only to books, so selecting
+// one of them leaves the other kind out of the results and the facets
+type Filters struct {
+	Category string
+	Level    string
+	Tags     []string
+	Year     int32
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facets are the number of matching results for every value the results can be refined by
+type Facets struct {
+	Categories []FacetCount `json:"categories"`
+	Tags       []FacetCount `json:"tags"`
+	Levels     []FacetCount `json:"levels"`
+	Years      []FacetCount `json:"years"`
+}
+
+// Results are one page of matching blogs and books, each paged by its own cursor
+// ordered by rank and id, the next cursors are empty when there are no more results
+type Results struct {
+	Blogs           []Blog
+	Books           []Book
+	BlogsNextCursor string
+	BooksNextCursor string
+	Facets          Facets
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+func nullYear(year int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: year, Valid: year != 0}
+}
+
+func afterRank(page *pagination.Page[float32]) sql.NullFloat64 {
+	rank, hasPrevious := page.AfterKey()
+	return sql.NullFloat64{Float64: float64(rank), Valid: hasPrevious}
+}
+
+// searchBlogs fetches the page of matching blogs when one is requested and always counts the facets
+func searchBlogs(query string, filters Filters, page *pagination.Page[float32], ctx context.Context, db *database.Queries, results *Results, facets *[]database.SearchBlogFacetsRow, searchErr *error, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	// searching blogs by full text rank combined with title similarity
+	if page != nil {
+		rows, err := db.SearchBlogs(ctx, database.SearchBlogsParams{
+			Query:     query,
+			Category:  nullString(filters.Category),
+			Tags:      filters.Tags,
+			Year:      nullYear(filters.Year),
+			AfterRank: afterRank(page),
+			AfterID:   page.AfterID(),
+			PageLimit: page.FetchLimit(),
+		})
+		if err != nil {
+			*searchErr = err
+			return
 		}
-	}
+		rows, results.BlogsNextCursor = pagination.Trim(*page, rows, func(row database.SearchBlogsRow) (float32, uuid.UUID) {
+			return row.Rank, row.ID
+		})
 
-	// searching blogs by title
-	for _, token := range tokens {
-		if blogsSearchByTitle, err := db.SearchBlogsByTitle(ctx, database.SearchBlogsByTitleParams{
-			Title:   "%" + token,
-			Title_2: "%" + token + "%",
-			Title_3: token + "%",
-		}); err == nil && blogsSearchByTitle != nil {
-			for _, value := range blogsSearchByTitle {
-				blogsResultSet.Add(Blog{
-					ID:           value.ID,
-					Title:        value.Title,
-					Brief:        value.Brief,
-					ThumbnailUrl: value.ThumbnailUrl,
-					Views:        value.Views,
-				})
-			}
+		for _, value := range rows {
+			results.Blogs = append(results.Blogs, Blog{
+				ID:             value.ID,
+				Title:          value.Title,
+				Brief:          value.Brief,
+				ThumbnailUrl:   value.ThumbnailUrl,
+				Views:          value.Views,
+				TitleHighlight: value.TitleHighlight,
+				Snippet:        value.Snippet,
+				Rank:           value.Rank,
+			})
 		}
 	}
 
-	wg.Done()
+	// counting all the matching blogs by category, tag and year
+	*facets, *searchErr = db.SearchBlogFacets(ctx, database.SearchBlogFacetsParams{
+		Query:    query,
+		Category: nullString(filters.Category),
+		Tags:     filters.Tags,
+		Year:     nullYear(filters.Year),
+	})
 }
 
-func searchBooks(tokens []string, ctx context.Context, db *database.Queries, booksResultSet *safeSet[Book], wg *sync.WaitGroup) {
-	// searching books by tags
-	if booksSearchByTags, err := db.SearchBooksByTags(ctx, tokens); err == nil && booksSearchByTags != nil {
-		for _, value := range booksSearchByTags {
-			booksResultSet.Add(Book{
+// searchBooks fetches the page of matching books when one is requested and always counts the facets
+func searchBooks(query string, filters Filters, page *pagination.Page[float32], ctx context.Context, db *database.Queries, results *Results, facets *[]database.SearchBookFacetsRow, searchErr *error, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	// searching books by full text rank combined with name similarity
+	if page != nil {
+		rows, err := db.SearchBooks(ctx, database.SearchBooksParams{
+			Query:     query,
+			Level:     nullString(filters.Level),
+			Tags:      filters.Tags,
+			Year:      nullYear(filters.Year),
+			AfterRank: afterRank(page),
+			AfterID:   page.AfterID(),
+			PageLimit: page.FetchLimit(),
+		})
+		if err != nil {
+			*searchErr = err
+			return
+		}
+		rows, results.BooksNextCursor = pagination.Trim(*page, rows, func(row database.SearchBooksRow) (float32, uuid.UUID) {
+			return row.Rank, row.ID
+		})
+
+		for _, value := range rows {
+			results.Books = append(results.Books, Book{
 				ID:            value.ID,
 				Name:          value.Name,
 				CoverImageUrl: value.CoverImageUrl,
+				NameHighlight: value.NameHighlight,
+				Snippet:       value.Snippet,
+				Rank:          value.Rank,
 			})
 		}
 	}
 
-	// searching books by title
-	for _, token := range tokens {
-		if booksSearchByTitle, err := db.SearchBooksByTitle(ctx, database.SearchBooksByTitleParams{
-			Name:   "%" + token,
-			Name_2: "%" + token + "%",
-			Name_3: token + "%",
-		}); err == nil && booksSearchByTitle != nil {
-			for _, value := range booksSearchByTitle {
-				booksResultSet.Add(Book{
-					ID:            value.ID,
-					Name:          value.Name,
-					CoverImageUrl: value.CoverImageUrl,
-				})
-			}
-		}
+	// counting all the matching books by level, tag and year
+	*facets, *searchErr = db.SearchBookFacets(ctx, database.SearchBookFacetsParams{
+		Query: query,
+		Level: nullString(filters.Level),
+		Tags:  filters.Tags,
+		Year:  nullYear(filters.Year),
+	})
+}
+
+// mergeFacetCounts adds up the counts of the same value and orders them from the most to the least frequent
+func mergeFacetCounts(counts map[string]int64) []FacetCount {
+	merged := make([]FacetCount, 0, len(counts))
+	for value, count := range counts {
+		merged = append(merged, FacetCount{
+			Value: value,
+			Count: count,
+		})
 	}
+	sort.Slice(merged, func(i, j int) bool {
+		if merged[i].Count != merged[j].Count {
+			return merged[i].Count > merged[j].Count
+		}
+		return merged[i].Value < merged[j].Value
+	})
 
-	wg.Done()
+	return merged
 }
 
-func Search(tokens []string, ctx context.Context, db *database.Queries) ([]*Blog, []*Book) {
+// Search runs the blogs and books searches concurrently, the query is parsed with
+// websearch syntax so "quoted phrases", or and -exclusions are supported, and the
+// results are ordered from the most to the least relevant. A nil page skips the
+// results of its kind and a filter applying to one kind only skips the other kind
+// entirely. The facets count every match narrowed by the filters, not only the
+// returned pages
+func Search(query string, filters Filters, blogsPage *pagination.Page[float32], booksPage *pagination.Page[float32], ctx context.Context, db *database.Queries) (Results, error) {
 	// declaring a wait group
 	var waitGroup sync.WaitGroup
 
-	// initializing a blogs result set
-	blogsResultSet := NewSafeSet[Blog]()
-
-	// initializing a books result set
-	booksResultSet := NewSafeSet[Book]()
+	// each search writes only its own results and error
+	blogResults, bookResults := Results{Blogs: make([]Blog, 0)}, Results{Books: make([]Book, 0)}
+	var blogFacets []database.SearchBlogFacetsRow
+	var bookFacets []database.SearchBookFacetsRow
+	var blogsErr, booksErr error
 
-	waitGroup.Add(2)
-	go searchBlogs(tokens, ctx, db, blogsResultSet, &waitGroup)
-	go searchBooks(tokens, ctx, db, booksResultSet, &waitGroup)
+	if filters.Level == "" {
+		waitGroup.Add(1)
+		go searchBlogs(query, filters, blogsPage, ctx, db, &blogResults, &blogFacets, &blogsErr, &waitGroup)
+	}
+	if filters.Category == "" {
+		waitGroup.Add(1)
+		go searchBooks(query, filters, booksPage, ctx, db, &bookResults, &bookFacets, &booksErr, &waitGroup)
+	}
 	waitGroup.Wait()
 
-	// creating the results
-	return blogsResultSet.Keys(), booksResultSet.Keys()
+	if blogsErr != nil {
+		return Results{}, blogsErr
+	}
+	if booksErr != nil {
+		return Results{}, booksErr
+	}
+
+	// grouping the facet counts of blogs and books, tags and years are shared by both
+	counts := map[string]map[string]int64{
+		"category": {},
+		"tag":      {},
+		"level":    {},
+		"year":     {},
+	}
+	for _, row := range blogFacets {
+		counts[row.Facet][row.Value] += row.Count
+	}
+	for _, row := range bookFacets {
+		counts[row.Facet][row.Value] += row.Count
+	}
+
+	return Results{
+		Blogs:           blogResults.Blogs,
+		Books:           bookResults.Books,
+		BlogsNextCursor: blogResults.BlogsNextCursor,
+		BooksNextCursor: bookResults.BooksNextCursor,
+		Facets: Facets{
+			Categories: mergeFacetCounts(counts["category"]),
+			Tags:       mergeFacetCounts(counts["tag"]),
+			Levels:     mergeFacetCounts(counts["level"]),
+			Years:      mergeFacetCounts(counts["year"]),
+		},
+	}, nil
 }
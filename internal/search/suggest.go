@@ -0,0 +1,161 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+)
+
+type Suggestions struct {
+	Titles     []string `json:"titles"`
+	BookNames  []string `json:"book_names"`
+	Tags       []string `json:"tags"`
+	Categories []string `json:"categories"`
+}
+
+type SuggestConfig struct {
+	// number of suggestions returned of each kind
+	MaxSuggestions int32
+
+	// duration for which the suggestions of a prefix are served from the cache
+	TTL time.Duration
+
+	// maximum number of cached prefixes
+	MaxEntries int
+}
+
+type suggestCacheEntry struct {
+	suggestions Suggestions
+	expiresAt   time.Time
+}
+
+// SuggestCache serves the suggestions of recently typed prefixes from memory, it has
+// to be invalidated whenever blogs, books or categories change
+type SuggestCache struct {
+	mutex          sync.RWMutex
+	entries        map[string]suggestCacheEntry
+	generation     uint64
+	maxSuggestions int32
+	ttl            time.Duration
+	maxEntries     int
+}
+
+func NewSuggestCache(config SuggestConfig) *SuggestCache {
+	suggestCache := &SuggestCache{
+		entries:        make(map[string]suggestCacheEntry),
+		maxSuggestions: config.MaxSuggestions,
+		ttl:            config.TTL,
+		maxEntries:     config.MaxEntries,
+	}
+	if suggestCache.maxSuggestions <= 0 {
+		suggestCache.maxSuggestions = 5
+	}
+	if suggestCache.ttl <= 0 {
+		suggestCache.ttl = 5 * time.Minute
+	}
+	if suggestCache.maxEntries <= 0 {
+		suggestCache.maxEntries = 10000
+	}
+
+	return suggestCache
+}
+
+// Invalidate drops every cached prefix so that the next suggestions reflect the changed content
+func (suggestCache *SuggestCache) Invalidate() {
+	suggestCache.mutex.Lock()
+	defer suggestCache.mutex.Unlock()
+	suggestCache.entries = make(map[string]suggestCacheEntry)
+	suggestCache.generation++
+}
+
+// Suggest returns the blog titles, book names, tags and categories closest to the typed
+// prefix, matching is case insensitive and tolerates typos through trigram similarity
+func (suggestCache *SuggestCache) Suggest(ctx context.Context, db *database.Queries, prefix string) (Suggestions, error) {
+	key := strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+
+	// serving the prefix from the cache if it was looked up recently
+	suggestCache.mutex.RLock()
+	entry, ok := suggestCache.entries[key]
+	generation := suggestCache.generation
+	suggestCache.mutex.RUnlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.suggestions, nil
+	}
+
+	suggestions, err := suggestCache.fetch(ctx, db, key)
+	if err != nil {
+		return suggestions, err
+	}
+
+	suggestCache.mutex.Lock()
+	defer suggestCache.mutex.Unlock()
+
+	// content changed while querying, caching the results would serve stale suggestions
+	if generation != suggestCache.generation {
+		return suggestions, nil
+	}
+
+	// making room for the new prefix by removing the expired ones first and any other after that
+	if len(suggestCache.entries) >= suggestCache.maxEntries {
+		now := time.Now()
+		for cachedKey, cachedEntry := range suggestCache.entries {
+			if now.After(cachedEntry.expiresAt) {
+				delete(suggestCache.entries, cachedKey)
+			}
+		}
+		for cachedKey := range suggestCache.entries {
+			if len(suggestCache.entries) < suggestCache.maxEntries {
+				break
+			}
+			delete(suggestCache.entries, cachedKey)
+		}
+	}
+	suggestCache.entries[key] = suggestCacheEntry{
+		suggestions: suggestions,
+		expiresAt:   time.Now().Add(suggestCache.ttl),
+	}
+
+	return suggestions, nil
+}
+
+func (suggestCache *SuggestCache) fetch(ctx context.Context, db *database.Queries, query string) (Suggestions, error) {
+	suggestions := Suggestions{}
+	var err error
+
+	if suggestions.Titles, err = db.SuggestBlogTitles(ctx, database.SuggestBlogTitlesParams{
+		Query:     query,
+		PageLimit: suggestCache.maxSuggestions,
+	}); err != nil {
+		return suggestions, err
+	}
+	if suggestions.BookNames, err = db.SuggestBookNames(ctx, database.SuggestBookNamesParams{
+		Query:     query,
+		PageLimit: suggestCache.maxSuggestions,
+	}); err != nil {
+		return suggestions, err
+	}
+	if suggestions.Tags, err = db.SuggestTags(ctx, database.SuggestTagsParams{
+		Query:     query,
+		PageLimit: suggestCache.maxSuggestions,
+	}); err != nil {
+		return suggestions, err
+	}
+	if suggestions.Categories, err = db.SuggestCategories(ctx, database.SuggestCategoriesParams{
+		Query:     query,
+		PageLimit: suggestCache.maxSuggestions,
+	}); err != nil {
+		return suggestions, err
+	}
+
+	// responding with empty lists instead of null
+	for _, list := range []*[]string{&suggestions.Titles, &suggestions.BookNames, &suggestions.Tags, &suggestions.Categories} {
+		if *list == nil {
+			*list = make([]string, 0)
+		}
+	}
+
+	return suggestions, nil
+}
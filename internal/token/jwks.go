@@ -0,0 +1,62 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+)
+
+// json web key as described in RFC 7517, only the public parts are ever published
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys, shared secrets are never included
+func (manager *Manager) JWKS() JWKSet {
+	keySet := JWKSet{
+		Keys: make([]JWK, 0, len(manager.verificationKeys)),
+	}
+
+	for keyID, key := range manager.verificationKeys {
+		switch publicKey := key.key.(type) {
+		case *rsa.PublicKey:
+			keySet.Keys = append(keySet.Keys, JWK{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				KeyID:     keyID,
+				Modulus:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keySet.Keys = append(keySet.Keys, JWK{
+				KeyType:   "OKP",
+				Use:       "sig",
+				Algorithm: key.method.Alg(),
+				KeyID:     keyID,
+				Curve:     "Ed25519",
+				X:         base64.RawURLEncoding.EncodeToString(publicKey),
+			})
+		}
+	}
+
+	// sorting for a stable response
+	sort.Slice(keySet.Keys, func(i, j int) bool {
+		return keySet.Keys[i].KeyID < keySet.Keys[j].KeyID
+	})
+
+	return keySet
+}
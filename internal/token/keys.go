@@ -0,0 +1,128 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// LoadManager creates a manager from the pem encoded keys in the directory, the file name
+// without extension is used as the key id. Private keys (PKCS#8 or PKCS#1) and public keys (PKIX)
+// of type RSA (RS256) and Ed25519 (EdDSA) are supported. Tokens are signed with signingKeyID and
+// verified with every key in the directory, which allows retiring keys by keeping only their public part.
+func LoadManager(directory string, signingKeyID string, config Config) (*Manager, error) {
+	files, err := filepath.Glob(filepath.Join(directory, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", directory)
+	}
+
+	manager := &Manager{
+		issuer:           config.Issuer,
+		audience:         config.Audience,
+		verificationKeys: make(map[string]verificationKey),
+	}
+
+	for _, file := range files {
+		keyID := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		publicKey, privateKey, err := parsePEMKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyID, err)
+		}
+
+		method, err := signingMethodFor(publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", keyID, err)
+		}
+
+		manager.verificationKeys[keyID] = verificationKey{
+			method: method,
+			key:    publicKey,
+		}
+		if !slices.Contains(manager.validMethods, method.Alg()) {
+			manager.validMethods = append(manager.validMethods, method.Alg())
+		}
+
+		if keyID == signingKeyID {
+			if privateKey == nil {
+				return nil, fmt.Errorf("signing key %s is not a private key", keyID)
+			}
+			manager.signingKey = signingKey{
+				id:     keyID,
+				method: method,
+				key:    privateKey,
+			}
+		}
+	}
+
+	if manager.signingKey.key == nil {
+		return nil, fmt.Errorf("signing key %s not found in %s", signingKeyID, directory)
+	}
+
+	return manager, nil
+}
+
+// returns the public key and, if the pem holds a private key, the private key
+func parsePEMKey(data []byte) (any, any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, nil, errors.New("invalid pem data")
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch key := privateKey.(type) {
+		case *rsa.PrivateKey:
+			return &key.PublicKey, key, nil
+		case ed25519.PrivateKey:
+			return key.Public(), key, nil
+		default:
+			return nil, nil, fmt.Errorf("unsupported private key type %T", privateKey)
+		}
+	case "RSA PRIVATE KEY":
+		privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return &privateKey.PublicKey, privateKey, nil
+	case "PUBLIC KEY":
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publicKey, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported pem block %s", block.Type)
+	}
+}
+
+func signingMethodFor(publicKey any) (jwt.SigningMethod, error) {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
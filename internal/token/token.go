@@ -0,0 +1,162 @@
+package token
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// lifetime of the access tokens issued on login, refresh and renewal
+const AccessTokenExpiry = 2 * time.Hour
+
+// claims carried by the access tokens
+type Claims struct {
+	UserID    string   `json:"uid"`
+	Role      string   `json:"role"`
+	SessionID string   `json:"sid"`
+	Scopes    []string `json:"scopes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Config struct {
+	Issuer   string
+	Audience string
+}
+
+// key used for verifying the tokens signed with the key id
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+// key used for signing new tokens
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    any
+}
+
+// Manager issues and verifies access tokens, tokens are signed with one key
+// and verified with any of the active keys so that keys can be rotated
+type Manager struct {
+	issuer           string
+	audience         string
+	signingKey       signingKey
+	verificationKeys map[string]verificationKey
+	validMethods     []string
+}
+
+// NewHMACManager creates a manager signing tokens with a shared secret using HS512
+func NewHMACManager(secret string, config Config) (*Manager, error) {
+	if secret == "" {
+		return nil, errors.New("empty token secret")
+	}
+
+	return &Manager{
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		signingKey: signingKey{
+			method: jwt.SigningMethodHS512,
+			key:    []byte(secret),
+		},
+		verificationKeys: map[string]verificationKey{
+			"": {
+				method: jwt.SigningMethodHS512,
+				key:    []byte(secret),
+			},
+		},
+		validMethods: []string{jwt.SigningMethodHS512.Alg()},
+	}, nil
+}
+
+// Issue signs a new token for the claims which expires after expiresIn
+func (manager *Manager) Issue(claims Claims, expiresIn time.Duration) (string, error) {
+	tokenID := make([]byte, 16)
+	if _, err := rand.Read(tokenID); err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        hex.EncodeToString(tokenID),
+		Issuer:    manager.issuer,
+		Audience:  jwt.ClaimStrings{manager.audience},
+		Subject:   claims.UserID,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+	}
+
+	token := jwt.NewWithClaims(manager.signingKey.method, claims)
+	if manager.signingKey.id != "" {
+		token.Header["kid"] = manager.signingKey.id
+	}
+
+	return token.SignedString(manager.signingKey.key)
+}
+
+// Parse verifies the token and returns its claims, claims are never returned along with an
+// error, an expired token returns an error wrapping jwt.ErrTokenExpired
+func (manager *Manager) Parse(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(manager.validMethods),
+		jwt.WithIssuer(manager.issuer),
+		jwt.WithAudience(manager.audience),
+		jwt.WithExpirationRequired(),
+	)
+
+	_, err := parser.ParseWithClaims(tokenString, claims, manager.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// ParseExpired verifies a token which has already expired and returns its claims so that the
+// access token of a session which is still active can be renewed, the signature, issuer and
+// audience are checked like in Parse
+func (manager *Manager) ParseExpired(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(manager.validMethods),
+		jwt.WithoutClaimsValidation(),
+	)
+
+	_, err := parser.ParseWithClaims(tokenString, claims, manager.keyFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	// checking the claims skipped by the parser
+	if manager.issuer != "" && claims.Issuer != manager.issuer {
+		return nil, jwt.ErrTokenInvalidIssuer
+	}
+	if manager.audience != "" && !slices.Contains(claims.Audience, manager.audience) {
+		return nil, jwt.ErrTokenInvalidAudience
+	}
+	if claims.ExpiresAt == nil || time.Now().Before(claims.ExpiresAt.Time) {
+		return nil, errors.New("token has not expired")
+	}
+
+	return claims, nil
+}
+
+// returns the key verifying the token from the key id in its header
+func (manager *Manager) keyFunc(token *jwt.Token) (any, error) {
+	keyID, _ := token.Header["kid"].(string)
+	key, exists := manager.verificationKeys[keyID]
+	if !exists {
+		return nil, fmt.Errorf("unknown key id %q", keyID)
+	}
+	if key.method.Alg() != token.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), keyID)
+	}
+
+	return key.key, nil
+}
@@ -0,0 +1,229 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testConfig = Config{
+	Issuer:   "artOfSoftwareEngineering",
+	Audience: "artOfSoftwareEngineering-api",
+}
+
+var testClaims = Claims{
+	UserID:    "8f9d6a2e-3b1c-4e5f-9a7b-1c2d3e4f5a6b",
+	Role:      "user",
+	SessionID: "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d",
+}
+
+func newTestHMACManager(t *testing.T, config Config) *Manager {
+	t.Helper()
+
+	manager, err := NewHMACManager("test-secret", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return manager
+}
+
+func TestIssueAndParse(t *testing.T) {
+	manager := newTestHMACManager(t, testConfig)
+
+	tokenString, err := manager.Issue(testClaims, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := manager.Parse(tokenString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != testClaims.UserID || claims.Role != testClaims.Role || claims.SessionID != testClaims.SessionID {
+		t.Fatalf("parsed claims %+v do not match the issued ones", claims)
+	}
+	if claims.Subject != testClaims.UserID {
+		t.Fatalf("subject %q, want %q", claims.Subject, testClaims.UserID)
+	}
+}
+
+func TestParseRejectsInvalidTokens(t *testing.T) {
+	manager := newTestHMACManager(t, testConfig)
+
+	issueWith := func(config Config, secret string, expiresIn time.Duration) string {
+		t.Helper()
+
+		issuer, err := NewHMACManager(secret, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenString, err := issuer.Issue(testClaims, expiresIn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tokenString
+	}
+
+	tests := []struct {
+		name        string
+		tokenString string
+		expected    error
+	}{
+		{"expired", issueWith(testConfig, "test-secret", -time.Minute), jwt.ErrTokenExpired},
+		{"wrong_issuer", issueWith(Config{Issuer: "other", Audience: testConfig.Audience}, "test-secret", time.Minute), jwt.ErrTokenInvalidIssuer},
+		{"wrong_audience", issueWith(Config{Issuer: testConfig.Issuer, Audience: "other"}, "test-secret", time.Minute), jwt.ErrTokenInvalidAudience},
+		{"expired_with_wrong_issuer", issueWith(Config{Issuer: "other", Audience: testConfig.Audience}, "test-secret", -time.Minute), jwt.ErrTokenInvalidIssuer},
+		{"wrong_secret", issueWith(testConfig, "other-secret", time.Minute), jwt.ErrTokenSignatureInvalid},
+		{"malformed", "not.a.token", jwt.ErrTokenMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := manager.Parse(tt.tokenString)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("got %v, want %v", err, tt.expected)
+			}
+			if claims != nil {
+				t.Errorf("claims returned for an invalid token")
+			}
+		})
+	}
+}
+
+func TestParseExpired(t *testing.T) {
+	manager := newTestHMACManager(t, testConfig)
+
+	issueWith := func(config Config, secret string, expiresIn time.Duration) string {
+		t.Helper()
+
+		issuer, err := NewHMACManager(secret, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenString, err := issuer.Issue(testClaims, expiresIn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return tokenString
+	}
+
+	claims, err := manager.ParseExpired(issueWith(testConfig, "test-secret", -time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != testClaims.UserID || claims.SessionID != testClaims.SessionID {
+		t.Fatalf("parsed claims %+v do not match the issued ones", claims)
+	}
+
+	// only the expiry is tolerated, every other check still applies
+	tests := []struct {
+		name        string
+		tokenString string
+		expected    error
+	}{
+		{"wrong_issuer", issueWith(Config{Issuer: "other", Audience: testConfig.Audience}, "test-secret", -time.Minute), jwt.ErrTokenInvalidIssuer},
+		{"wrong_audience", issueWith(Config{Issuer: testConfig.Issuer, Audience: "other"}, "test-secret", -time.Minute), jwt.ErrTokenInvalidAudience},
+		{"wrong_secret", issueWith(testConfig, "other-secret", -time.Minute), jwt.ErrTokenSignatureInvalid},
+		{"not_expired", issueWith(testConfig, "test-secret", time.Minute), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := manager.ParseExpired(tt.tokenString)
+			if err == nil || (tt.expected != nil && !errors.Is(err, tt.expected)) {
+				t.Errorf("got %v, want %v", err, tt.expected)
+			}
+			if claims != nil {
+				t.Errorf("claims returned for a rejected token")
+			}
+		})
+	}
+}
+
+func TestParseRejectsUnsignedToken(t *testing.T) {
+	manager := newTestHMACManager(t, testConfig)
+
+	claims := testClaims
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    testConfig.Issuer,
+		Audience:  jwt.ClaimStrings{testConfig.Audience},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}
+	tokenString, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = manager.Parse(tokenString); err == nil {
+		t.Fatal("unsigned token accepted")
+	}
+}
+
+func TestLoadManagerRotatesKeys(t *testing.T) {
+	directory := t.TempDir()
+	writeEd25519Key(t, directory, "old")
+	writeEd25519Key(t, directory, "new")
+
+	oldManager, err := LoadManager(directory, "old", testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldManager.Issue(testClaims, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens signed with the retired key are still verified by the manager signing with the new key
+	newManager, err := LoadManager(directory, "new", testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newManager.Parse(oldToken); err != nil {
+		t.Fatalf("token signed with the old key rejected: %v", err)
+	}
+
+	// once the old key is removed its tokens are rejected
+	if err = os.Remove(filepath.Join(directory, "old.pem")); err != nil {
+		t.Fatal(err)
+	}
+	newManager, err = LoadManager(directory, "new", testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = newManager.Parse(oldToken); err == nil {
+		t.Fatal("token signed with a removed key accepted")
+	}
+
+	if keys := newManager.JWKS().Keys; len(keys) != 1 || keys[0].KeyID != "new" {
+		t.Fatalf("unexpected jwks %+v", keys)
+	}
+}
+
+func writeEd25519Key(t *testing.T, directory string, keyID string) {
+	t.Helper()
+
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(filepath.Join(directory, keyID+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
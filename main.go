@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/harshvardha/artOfSoftwareEngineering/controllers"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/cache"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/contentfilter"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/mailer"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/permission"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/search"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
 	"github.com/harshvardha/artOfSoftwareEngineering/middlewares"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 	"github.com/joho/godotenv"
@@ -20,10 +32,43 @@ func main() {
 	// loading all the required env variables
 	godotenv.Load()
 
-	// loading jwt access secret key
-	jwtSecret := os.Getenv("ACCESS_TOKEN_SECRET")
-	if jwtSecret == "" {
-		log.Fatal("jwt_secret variable not set")
+	// loading the issuer and audience of the access tokens
+	tokenConfig := token.Config{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+	}
+	if tokenConfig.Issuer == "" {
+		tokenConfig.Issuer = "http://localhost:8080"
+	}
+	if tokenConfig.Audience == "" {
+		tokenConfig.Audience = tokenConfig.Issuer
+	}
+
+	// loading the access token signing keys, asymmetric keys are loaded from JWT_KEYS_DIR
+	// and the shared ACCESS_TOKEN_SECRET is used only when no key directory is set
+	var tokenManager *token.Manager
+	if jwtKeysDir := os.Getenv("JWT_KEYS_DIR"); jwtKeysDir != "" {
+		signingKeyID := os.Getenv("JWT_SIGNING_KEY_ID")
+		if signingKeyID == "" {
+			log.Fatal("JWT_SIGNING_KEY_ID not set")
+		}
+
+		manager, err := token.LoadManager(jwtKeysDir, signingKeyID, tokenConfig)
+		if err != nil {
+			log.Fatal("Error loading jwt keys: ", err)
+		}
+		tokenManager = manager
+	} else {
+		jwtSecret := os.Getenv("ACCESS_TOKEN_SECRET")
+		if jwtSecret == "" {
+			log.Fatal("jwt_secret variable not set")
+		}
+
+		manager, err := token.NewHMACManager(jwtSecret, tokenConfig)
+		if err != nil {
+			log.Fatal("Error creating token manager: ", err)
+		}
+		tokenManager = manager
 	}
 
 	// loading port number
@@ -43,25 +88,38 @@ func main() {
 	if fromEmail == "" {
 		log.Fatal("From Email not set")
 	}
-	emailSubject := os.Getenv("EMAIL_SUBJECT")
-	if emailSubject == "" {
-		log.Fatal("Email Subject not set")
-	}
-	emailBody := os.Getenv("EMAIL_BODY")
-	if emailBody == "" {
-		log.Fatal("Email Body not set")
-	}
-	smtpHost := os.Getenv("SMTP_HOST")
-	if smtpHost == "" {
-		log.Fatal("SMTP HOST not set")
-	}
-	smtpPort := os.Getenv("SMTP_PORT")
-	if smtpPort == "" {
-		log.Fatal("SMTP PORT not set")
-	}
-	appPassword := os.Getenv("GMAIL_APP_PASSWORD")
-	if appPassword == "" {
-		log.Fatal("App Password not set")
+
+	// selecting the mail transport used to deliver otps, defaults to smtp
+	var otpMailer mailer.Mailer
+	switch mailDriver := os.Getenv("MAIL_DRIVER"); mailDriver {
+	case "", "smtp":
+		smtpHost := os.Getenv("SMTP_HOST")
+		if smtpHost == "" {
+			log.Fatal("SMTP HOST not set")
+		}
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			log.Fatal("SMTP PORT not set")
+		}
+		appPassword := os.Getenv("GMAIL_APP_PASSWORD")
+		if appPassword == "" {
+			log.Fatal("App Password not set")
+		}
+		otpMailer = mailer.NewSMTPMailer(smtpHost, smtpPort, fromEmail, appPassword)
+	case "file":
+		mailDirectory := os.Getenv("MAIL_DIR")
+		if mailDirectory == "" {
+			log.Fatal("Mail Directory not set")
+		}
+		fileMailer, err := mailer.NewFileMailer(mailDirectory)
+		if err != nil {
+			log.Fatal("Unable to create mail directory: ", err)
+		}
+		otpMailer = fileMailer
+	case "memory":
+		otpMailer = mailer.NewRecorder()
+	default:
+		log.Fatal("Invalid Mail Driver: ", mailDriver)
 	}
 
 	// creating database connection
@@ -69,6 +127,161 @@ func main() {
 	if err != nil {
 		log.Fatal("Error Connecting to Database: ", err)
 	}
+	db := database.New(dbConnection)
+
+	// loading otp cache limits
+	otpSweepInterval := time.Minute
+	if value := os.Getenv("OTP_SWEEP_INTERVAL"); value != "" {
+		otpSweepInterval, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid OTP Sweep Interval: ", err)
+		}
+	}
+	otpMaxEntries := 10000
+	if value := os.Getenv("OTP_MAX_ENTRIES"); value != "" {
+		otpMaxEntries, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid OTP Max Entries: ", err)
+		}
+	}
+
+	// loading otp brute force protection limits, the otp cache falls back to its defaults when unset
+	var otpMaxVerifyAttempts, otpSendQuota int
+	var otpSendQuotaWindow time.Duration
+	if value := os.Getenv("OTP_MAX_VERIFY_ATTEMPTS"); value != "" {
+		otpMaxVerifyAttempts, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid OTP Max Verify Attempts: ", err)
+		}
+	}
+	if value := os.Getenv("OTP_SEND_QUOTA"); value != "" {
+		otpSendQuota, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid OTP Send Quota: ", err)
+		}
+	}
+	if value := os.Getenv("OTP_SEND_QUOTA_WINDOW"); value != "" {
+		otpSendQuotaWindow, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid OTP Send Quota Window: ", err)
+		}
+	}
+
+	// loading login throttling limits, the login handler falls back to its defaults when unset
+	loginThrottle := controllers.LoginThrottleConfig{}
+	if value := os.Getenv("LOGIN_MAX_ACCOUNT_FAILURES"); value != "" {
+		loginThrottle.MaxAccountFailures, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Login Max Account Failures: ", err)
+		}
+	}
+	if value := os.Getenv("LOGIN_MAX_IP_FAILURES"); value != "" {
+		loginThrottle.MaxIPFailures, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Login Max IP Failures: ", err)
+		}
+	}
+	if value := os.Getenv("LOGIN_FAILURE_WINDOW"); value != "" {
+		loginThrottle.FailureWindow, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid Login Failure Window: ", err)
+		}
+	}
+	if value := os.Getenv("LOGIN_BASE_LOCKOUT"); value != "" {
+		loginThrottle.BaseLockout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid Login Base Lockout: ", err)
+		}
+	}
+	if value := os.Getenv("LOGIN_MAX_LOCKOUT"); value != "" {
+		loginThrottle.MaxLockout, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid Login Max Lockout: ", err)
+		}
+	}
+
+	// loading comment options, the comment handlers fall back to their defaults when unset
+	commentConfig := controllers.CommentConfig{
+		AutoApproveAfter: controllers.DefaultCommentAutoApproveAfter,
+	}
+	if value := os.Getenv("COMMENT_MAX_DEPTH"); value != "" {
+		commentConfig.MaxDepth, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Max Depth: ", err)
+		}
+	}
+	if value := os.Getenv("COMMENT_AUTO_APPROVE_AFTER"); value != "" {
+		commentConfig.AutoApproveAfter, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Auto Approve After: ", err)
+		}
+		if commentConfig.AutoApproveAfter < 0 {
+			log.Fatal("Invalid Comment Auto Approve After: ", value)
+		}
+	}
+
+	// loading the comment content filter options, word lists are files with one word or phrase per line
+	contentFilterConfig := contentfilter.Config{}
+	if value := os.Getenv("COMMENT_BLOCKED_WORDS_FILE"); value != "" {
+		contentFilterConfig.BlockedWords, err = contentfilter.LoadWordList(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Blocked Words File: ", err)
+		}
+	}
+	if value := os.Getenv("COMMENT_FLAGGED_WORDS_FILE"); value != "" {
+		contentFilterConfig.FlaggedWords, err = contentfilter.LoadWordList(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Flagged Words File: ", err)
+		}
+	}
+	if value := os.Getenv("COMMENT_MAX_LINKS"); value != "" {
+		contentFilterConfig.MaxLinks, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Max Links: ", err)
+		}
+	}
+	if value := os.Getenv("COMMENT_DUPLICATE_WINDOW"); value != "" {
+		contentFilterConfig.DuplicateWindow, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid Comment Duplicate Window: ", err)
+		}
+	}
+
+	// loading reporting options, the report handlers fall back to their defaults when unset
+	reportConfig := controllers.ReportConfig{}
+	if value := os.Getenv("REPORT_AUTO_HIDE_THRESHOLD"); value != "" {
+		reportConfig.AutoHideThreshold, err = strconv.Atoi(value)
+		if err != nil {
+			log.Fatal("Invalid Report Auto Hide Threshold: ", err)
+		}
+	}
+
+	// loading search suggestion limits, the suggest cache falls back to its defaults when unset
+	suggestConfig := search.SuggestConfig{}
+	if value := os.Getenv("SEARCH_SUGGEST_LIMIT"); value != "" {
+		maxSuggestions, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			log.Fatal("Invalid Search Suggest Limit: ", err)
+		}
+		suggestConfig.MaxSuggestions = int32(maxSuggestions)
+	}
+	if value := os.Getenv("SEARCH_SUGGEST_CACHE_TTL"); value != "" {
+		suggestConfig.TTL, err = time.ParseDuration(value)
+		if err != nil {
+			log.Fatal("Invalid Search Suggest Cache TTL: ", err)
+		}
+	}
+
+	// selecting the otp store, defaults to in process memory store
+	var otpStore cache.OTPStore
+	switch otpStoreType := os.Getenv("OTP_STORE"); otpStoreType {
+	case "", "memory":
+		otpStore = cache.NewMemoryOTPStore(otpMaxEntries)
+	case "postgres":
+		otpStore = cache.NewPostgresOTPStore(db)
+	default:
+		log.Fatal("Invalid OTP Store: ", otpStoreType)
+	}
 
 	// setting data validator
 	dataValidator := validator.New()
@@ -88,113 +301,158 @@ func main() {
 	// registering new tags validator
 	dataValidator.RegisterValidation("tags", utility.NoDuplicatesTagsValidator)
 
+	// creating otp cache
+	otpCache := cache.NewOTPCache(otpStore, otpMailer, cache.Config{
+		FromEmail:         fromEmail,
+		SweepInterval:     otpSweepInterval,
+		MaxVerifyAttempts: otpMaxVerifyAttempts,
+		SendQuota:         otpSendQuota,
+		SendQuotaWindow:   otpSendQuotaWindow,
+	})
+	defer otpCache.Close()
+
+	// publishing otp cache metrics
+	expvar.Publish("otp_cache", expvar.Func(func() any {
+		return otpCache.Metrics()
+	}))
+
 	// setting apiConfig
 	apiConfig := controllers.ApiConfig{
-		DB:            database.New(dbConnection),
-		JwtSecret:     jwtSecret,
-		OtpCache:      cache.NewOTPCache(fromEmail, emailSubject, emailBody, smtpHost, smtpPort, appPassword),
+		DB:            db,
+		TokenManager:  tokenManager,
+		OtpCache:      otpCache,
 		DataValidator: dataValidator,
-	}
-
-	routes := map[string][]string{
-		"user": {
-			"/api/v1/book/filter",
-			"/api/v1/book/all",
-			"/api/v1/book/review",
-			"/api/v1/user/update/email",
-			"/api/v1/user/update/password",
-			"/api/v1/user/update/other",
-			"/api/v1/user/account/remove",
-			"/api/v1/user/search",
-			"/api/v1/user",
-			"/api/v1/blog/view/increment",
-			"/api/v1/blog/likedislike",
-			"/api/v1/blog",
-			"/api/v1/blog/category",
-			"/api/v1/comment/create",
-			"/api/v1/comment/update",
-			"/api/v1/comment/remove",
-			"/api/v1/comment/all",
-		},
-		"nil_IDAndRole": {
-			"/api/v1/book/add",
-			"/api/v1/book/review",
-			"/api/v1/book/all",
-			"/api/v1/book/filter",
-			"/api/v1/book/update",
-			"/api/v1/book/remove",
-			"/api/v1/blog/update",
-			"/api/v1/blog/remove",
-			"/api/v1/blog/category",
-			"/api/v1/blog/view/increment",
-			"/api/v1/comment/all",
-		},
-		"nil_accessToken": {
-			"/api/v1/user/update/email",
-			"/api/v1/user/update/password",
-			"/api/v1/user/account/remove",
-		},
+		LoginThrottle: loginThrottle,
+		Comments:      commentConfig,
+		ContentFilter: contentfilter.NewWordListFilter(db, contentFilterConfig),
+		Reports:       reportConfig,
+		SuggestCache:  search.NewSuggestCache(suggestConfig),
 	}
 
 	// creating new request redirecting multiplexer
 	mux := http.NewServeMux()
 
+	// every endpoint is registered as public or with the permissions it requires
+	registry := middlewares.NewRegistry(mux, apiConfig.TokenManager, apiConfig.DB)
+
 	// creating a healthz endpoint for server status check
-	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
+	registry.Public("GET", "/api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
 
+	// exposing runtime and otp cache metrics to admins only
+	registry.Protected("GET", "/api/metrics", expvar.Handler().ServeHTTP, permission.MetricsRead)
+
+	// publishing the public keys other services can verify the access tokens with
+	registry.Public("GET", "/.well-known/jwks.json", apiConfig.HandleJWKS)
+
 	// api endpoints for authentication
-	mux.HandleFunc("GET /api/v1/auth/otp/send", apiConfig.HandleSendOTP)
-	mux.HandleFunc("POST /api/v1/auth/register", apiConfig.HandleRegisterUser)
-	mux.HandleFunc("GET /api/v1/auth/otp/resend", apiConfig.HandleResendOTP)
-	mux.HandleFunc("POST /api/v1/auth/login", apiConfig.HandleLogin)
+	registry.Public("GET", "/api/v1/auth/otp/send", apiConfig.HandleSendOTP)
+	registry.Public("POST", "/api/v1/auth/register", apiConfig.HandleRegisterUser)
+	registry.Public("GET", "/api/v1/auth/otp/resend", apiConfig.HandleResendOTP)
+	registry.Public("POST", "/api/v1/auth/login", apiConfig.HandleLogin)
+	registry.Public("POST", "/api/v1/auth/refresh", apiConfig.HandleRefreshToken)
+	registry.Public("POST", "/api/v1/auth/password/forgot", apiConfig.HandleForgotPassword)
+	registry.Public("POST", "/api/v1/auth/password/reset", apiConfig.HandleResetPassword)
+	registry.Protected("POST", "/api/v1/auth/logout", apiConfig.HandleLogout, permission.SessionManage)
+	registry.Protected("POST", "/api/v1/auth/logout/all", apiConfig.HandleLogoutAll, permission.SessionManage)
+	registry.Protected("GET", "/api/v1/auth/sessions", apiConfig.HandleGetSessions, permission.SessionManage)
+	registry.Protected("DELETE", "/api/v1/auth/sessions/remove", apiConfig.HandleRevokeSession, permission.SessionManage)
 
 	// api endpoints for books
-	mux.HandleFunc("POST /api/v1/book/add", middlewares.ValidateJWT(apiConfig.HandleAddBook, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/book/update", middlewares.ValidateJWT(apiConfig.HandleUpdateBook, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("DELETE /api/v1/book/remove", middlewares.ValidateJWT(apiConfig.HandleRemoveBook, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/book/filter", middlewares.ValidateJWT(apiConfig.HandleFilterBooksByLevel, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/book/all", middlewares.ValidateJWT(apiConfig.HandleGetAllBooks, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/book/review", middlewares.ValidateJWT(apiConfig.HandleGetReviewByBookID, apiConfig.JwtSecret, apiConfig.DB, routes))
+	registry.Protected("POST", "/api/v1/book/add", apiConfig.HandleAddBook, permission.BookCreate)
+	registry.Protected("PUT", "/api/v1/book/update", apiConfig.HandleUpdateBook, permission.BookUpdate)
+	registry.Protected("DELETE", "/api/v1/book/remove", apiConfig.HandleRemoveBook, permission.BookDelete)
+	registry.Optional("GET", "/api/v1/book/filter", apiConfig.HandleFilterBooksByLevel)
+	registry.Optional("GET", "/api/v1/book/all", apiConfig.HandleGetAllBooks)
+	registry.Optional("GET", "/api/v1/books/{id}/review", apiConfig.HandleGetReviewByBookID)
 
 	// api endpoints for user
-	mux.HandleFunc("PUT /api/v1/user/update/email", middlewares.ValidateJWT(apiConfig.HandleUpdateEmail, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/user/update/password", middlewares.ValidateJWT(apiConfig.HandleUpdatePassword, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/user/update/other", middlewares.ValidateJWT(apiConfig.HandleUpdateOtherDetails, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("DELETE /api/v1/user/account/remove", middlewares.ValidateJWT(apiConfig.HandleRemoveUserAccount, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/user", middlewares.ValidateJWT(apiConfig.HandleGetUserByID, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/user/search", middlewares.ValidateJWT(apiConfig.HandleUserSearch, apiConfig.JwtSecret, apiConfig.DB, routes))
+	registry.Protected("PUT", "/api/v1/user/update/email", apiConfig.HandleUpdateEmail, permission.AccountManage)
+	registry.Protected("PUT", "/api/v1/user/update/password", apiConfig.HandleUpdatePassword, permission.AccountManage)
+	registry.Protected("PUT", "/api/v1/user/update/other", apiConfig.HandleUpdateOtherDetails, permission.AccountManage)
+	registry.Protected("DELETE", "/api/v1/user/account/remove", apiConfig.HandleRemoveUserAccount, permission.AccountManage)
+	registry.Protected("GET", "/api/v1/user", apiConfig.HandleGetUserByID, permission.UserRead)
+	registry.Protected("GET", "/api/v1/user/search", apiConfig.HandleUserSearch, permission.UserRead)
+	registry.Protected("POST", "/api/v1/user/unlock", apiConfig.HandleUnlockAccount, permission.UserUnlock)
+
+	// api endpoints for search
+	registry.Optional("GET", "/api/v1/search/suggest", apiConfig.HandleSearchSuggest)
 
 	// api endpoints for category
-	mux.HandleFunc("POST /api/v1/category/create", middlewares.ValidateJWT(apiConfig.HandleCreateCategory, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/category/update", middlewares.ValidateJWT(apiConfig.HandleUpdateCategory, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("DELETE /api/v1/category/remove", middlewares.ValidateJWT(apiConfig.HandleRemoveCategory, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/category/all", middlewares.ValidateJWT(apiConfig.HandleGetAllCategories, apiConfig.JwtSecret, apiConfig.DB, routes))
+	registry.Protected("POST", "/api/v1/category/create", apiConfig.HandleCreateCategory, permission.CategoryCreate)
+	registry.Protected("PUT", "/api/v1/category/update", apiConfig.HandleUpdateCategory, permission.CategoryUpdate)
+	registry.Protected("DELETE", "/api/v1/category/remove", apiConfig.HandleRemoveCategory, permission.CategoryDelete)
+	registry.Optional("GET", "/api/v1/category/all", apiConfig.HandleGetAllCategories)
 
 	// api endpoints for blogs
-	mux.HandleFunc("POST /api/v1/blog/create", middlewares.ValidateJWT(apiConfig.HandleCreateBlog, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/blog/update", middlewares.ValidateJWT(apiConfig.HandleUpdateBlog, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("DELETE /api/v1/blog/remove", middlewares.ValidateJWT(apiConfig.HandleRemoveBlog, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/blog/category", middlewares.ValidateJWT(apiConfig.HandleGetBlogsByCategory, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/blog", middlewares.ValidateJWT(apiConfig.HandleGetBlogByID, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/blog/likedislike", middlewares.ValidateJWT(apiConfig.HandleLikeOrDislike, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/blog/views/increment", middlewares.ValidateJWT(apiConfig.HandleIncrementView, apiConfig.JwtSecret, apiConfig.DB, routes))
+	registry.Protected("POST", "/api/v1/blog/create", apiConfig.HandleCreateBlog, permission.BlogCreate)
+	registry.Protected("PUT", "/api/v1/blog/update", apiConfig.HandleUpdateBlog, permission.BlogUpdate)
+	registry.Protected("DELETE", "/api/v1/blog/remove", apiConfig.HandleRemoveBlog, permission.BlogDelete)
+	registry.Optional("GET", "/api/v1/categories/{name}/blogs", apiConfig.HandleGetBlogsByCategory)
+	registry.Optional("GET", "/api/v1/blogs/{id}", apiConfig.HandleGetBlogByID)
+	registry.Optional("GET", "/api/v1/blog/filter", apiConfig.HandleFilterBlogs)
+	registry.Protected("PUT", "/api/v1/blog/likedislike", apiConfig.HandleLikeOrDislike, permission.BlogLike)
+	registry.Protected("PUT", "/api/v1/blog/views/increment", apiConfig.HandleIncrementView, permission.BlogView)
 
 	// api endpoints for comments
-	mux.HandleFunc("POST /api/v1/comment/create", middlewares.ValidateJWT(apiConfig.HandleCreateComment, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("PUT /api/v1/comment/update", middlewares.ValidateJWT(apiConfig.HandleUpdateComment, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("DELETE /api/v1/comment/remove", middlewares.ValidateJWT(apiConfig.HandleRemoveComment, apiConfig.JwtSecret, apiConfig.DB, routes))
-	mux.HandleFunc("GET /api/v1/comment/all", middlewares.ValidateJWT(apiConfig.HandleGetAllCommentsByBlogID, apiConfig.JwtSecret, apiConfig.DB, routes))
+	registry.Protected("POST", "/api/v1/comment/create", apiConfig.HandleCreateComment, permission.CommentCreate)
+	registry.Protected("PUT", "/api/v1/comment/update", apiConfig.HandleUpdateComment, permission.CommentUpdate)
+	registry.Protected("DELETE", "/api/v1/comment/remove", apiConfig.HandleRemoveComment, permission.CommentDelete)
+	registry.Protected("PUT", "/api/v1/comment/likedislike", apiConfig.HandleLikeOrDislikeComment, permission.CommentLike)
+	registry.Optional("GET", "/api/v1/blogs/{id}/comments", apiConfig.HandleGetAllCommentsByBlogID)
+
+	// api endpoints for comment moderation
+	registry.Protected("GET", "/api/v1/comment/moderation/queue", apiConfig.HandleGetModerationQueue, permission.CommentModerate)
+	registry.Protected("PUT", "/api/v1/comment/moderation", apiConfig.HandleModerateComments, permission.CommentModerate)
+	registry.Protected("DELETE", "/api/v1/comment/moderation/remove", apiConfig.HandleRemoveCommentAsModerator, permission.CommentModerate)
+
+	// api endpoints for reporting blogs and comments
+	registry.Protected("POST", "/api/v1/report", apiConfig.HandleCreateReport, permission.ReportCreate)
+	registry.Protected("GET", "/api/v1/report/all", apiConfig.HandleGetReportedTargets, permission.ReportManage)
+	registry.Protected("GET", "/api/v1/report/target", apiConfig.HandleGetReportsByTarget, permission.ReportManage)
+	registry.Protected("PUT", "/api/v1/report/resolve", apiConfig.HandleResolveReports, permission.ReportManage)
+
+	// deprecated aliases of the endpoints above which took their parameters in a json body
+	registry.Optional("GET", "/api/v1/book/review", apiConfig.HandleGetReviewByBookIDFromBody)
+	registry.Optional("GET", "/api/v1/blog/category", apiConfig.HandleGetBlogsByCategoryFromBody)
+	registry.Optional("GET", "/api/v1/blog", apiConfig.HandleGetBlogByIDFromBody)
+	registry.Optional("GET", "/api/v1/comment/all", apiConfig.HandleGetAllCommentsByBlogIDFromBody)
+
+	// api endpoints for roles
+	registry.Protected("POST", "/api/v1/role/create", apiConfig.HandleCreateRole, permission.RoleManage)
+	registry.Protected("PUT", "/api/v1/role/update", apiConfig.HandleUpdateRole, permission.RoleManage)
+	registry.Protected("DELETE", "/api/v1/role/remove", apiConfig.HandleRemoveRole, permission.RoleManage)
+	registry.Protected("GET", "/api/v1/role/all", apiConfig.HandleGetAllRoles, permission.RoleManage)
+	registry.Protected("PUT", "/api/v1/role/assign", apiConfig.HandleAssignRole, permission.RoleManage)
+	registry.Protected("GET", "/api/v1/role/audit", apiConfig.HandleGetRoleAuditLog, permission.RoleManage)
+
+	// api endpoints for permissions
+	registry.Protected("POST", "/api/v1/permission/grant", apiConfig.HandleGrantPermission, permission.PermissionManage)
+	registry.Protected("DELETE", "/api/v1/permission/revoke", apiConfig.HandleRevokePermission, permission.PermissionManage)
+	registry.Protected("GET", "/api/v1/permission/all", apiConfig.HandleGetRolePermissions, permission.PermissionManage)
 
 	// starting the server
 	server := &http.Server{
 		Handler: mux,
 		Addr:    ":" + portNo,
 	}
-	if err := server.ListenAndServe(); err != nil {
+
+	// shutting down the server gracefully on interrupt
+	shutdownCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-shutdownCtx.Done()
+		timeoutCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(timeoutCtx); err != nil {
+			log.Println("Error shutting down server: ", err)
+		}
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatal("Unable to start server: ", err)
 	}
 }
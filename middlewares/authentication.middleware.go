@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"database/sql"
 	"errors"
 	"net/http"
 	"strings"
@@ -10,107 +11,114 @@ import (
 	"github.com/google/uuid"
 	"github.com/harshvardha/artOfSoftwareEngineering/controllers"
 	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 )
 
-func getSubjects(tokenSubjects string) (map[string]string, error) {
-	subjects := strings.Split(tokenSubjects, ",")
-	if len(subjects) == 0 {
-		return nil, errors.New("no subject found")
+// validates the access token of the request and the session it was issued for and returns
+// the user it belongs to along with a new access token if the old one had expired while its
+// session is still active, on failure the error response is already written
+func authenticate(w http.ResponseWriter, r *http.Request, tokenManager *token.Manager, db *database.Queries) (*controllers.IDAndRole, string, bool) {
+	// extracting JWT token from request header
+	authHeader := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(authHeader) != 2 {
+		utility.RespondWithError(w, http.StatusNotAcceptable, "malformed request auth header")
+		return nil, "", false
 	}
 
-	subjectsMap := make(map[string]string, len(subjects))
-	var temp []string
-	for _, subject := range subjects {
-		temp = strings.Split(subject, ":")
-		if len(temp) == 0 {
-			return nil, errors.New("not all claims found")
-		}
-		subjectsMap[temp[0]] = temp[1]
+	// verifying the token, expired tokens are renewed below only if their session is still active
+	claims, err := tokenManager.Parse(authHeader[1])
+	expired := errors.Is(err, jwt.ErrTokenExpired)
+	if expired {
+		claims, err = tokenManager.ParseExpired(authHeader[1])
+	}
+	if err != nil {
+		utility.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return nil, "", false
+	}
+
+	// extracting userID from token claims
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusNotAcceptable, err.Error())
+		return nil, "", false
+	}
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusUnauthorized, "invalid session")
+		return nil, "", false
+	}
+
+	// checking the session on every request so that logging out or revoking
+	// a session locks out its access tokens before they expire
+	session, err := db.GetSessionByID(r.Context(), sessionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && session.UserID != userID) {
+		utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
+		return nil, "", false
+	}
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return nil, "", false
+	}
+	if session.RevokedAt.Valid || time.Now().UTC().After(session.ExpiresAt) {
+		utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
+		return nil, "", false
+	}
+
+	// the current role of the user is used so that role changes apply right away
+	UserRoleAndId := &controllers.IDAndRole{
+		ID:        userID,
+		Role:      session.RoleName,
+		SessionID: sessionID,
+	}
+	if !expired {
+		return UserRoleAndId, "", true
+	}
+
+	// creating new access token for the active session with the current role of the user
+	newAccessToken, err := tokenManager.Issue(token.Claims{
+		UserID:    userID.String(),
+		Role:      session.RoleName,
+		SessionID: sessionID.String(),
+		Scopes:    claims.Scopes,
+	}, token.AccessTokenExpiry)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return nil, "", false
 	}
 
-	return subjectsMap, nil
+	return UserRoleAndId, newAccessToken, true
 }
 
-func ValidateJWT(handler authenticatedRequestHandler, tokenSecret string, db *database.Queries, routes map[string][]string) http.HandlerFunc {
+func ValidateJWT(handler http.HandlerFunc, tokenManager *token.Manager, db *database.Queries, permissions []string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// extracting JWT token from request header
-		authHeader := strings.Split(r.Header.Get("Authorization"), " ")
-		if len(authHeader) != 2 {
-			utility.RespondWithError(w, http.StatusNotAcceptable, "malformed request auth header")
+		UserRoleAndId, newAccessToken, ok := authenticate(w, r, tokenManager, db)
+		if !ok {
 			return
 		}
 
-		// initializing an empty struct to parse claims
-		jwtClaims := jwt.RegisteredClaims{}
-		token, parseError := jwt.ParseWithClaims(authHeader[1], &jwtClaims, func(token *jwt.Token) (any, error) {
-			return []byte(tokenSecret), nil
-		})
+		// calling the authorization middleware to check whether the user is authorized to access this endpoint
+		userAuthorization(w, r, handler, db, permissions, UserRoleAndId, newAccessToken)
+	}
+}
 
-		// extracting userID from token claims
-		subjects, err := token.Claims.GetSubject()
-		if err != nil {
-			utility.RespondWithError(w, http.StatusUnauthorized, err.Error())
-			return
-		}
-		parsedSubjects, err := getSubjects(subjects)
-		if err != nil {
-			utility.RespondWithError(w, http.StatusUnauthorized, err.Error())
+// OptionalJWT lets requests without an access token through anonymously, requests
+// carrying a token are authenticated like in ValidateJWT so handlers can personalize the response
+func OptionalJWT(handler http.HandlerFunc, tokenManager *token.Manager, db *database.Queries) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			handler(w, r)
 			return
 		}
 
-		userID, err := uuid.Parse(parsedSubjects["user_id"])
-		if err != nil {
-			utility.RespondWithError(w, http.StatusNotAcceptable, err.Error())
+		UserRoleAndId, newAccessToken, ok := authenticate(w, r, tokenManager, db)
+		if !ok {
 			return
 		}
-		UserRoleAndId := controllers.IDAndRole{
-			ID:   userID,
-			Role: parsedSubjects["role"],
-		}
 
-		if parseError != nil {
-			// checking if the access token is expired or not
-			// if access token is expired then we will check if the refresh token is expired or not
-			// if refresh token is not expired then we will create a new access token and continue
-			// if refresh token is expired then we will ask user to login again
-			if errors.Is(parseError, jwt.ErrTokenExpired) {
-				refreshTokenExpiresAt, err := db.GetRefreshTokenExpirationTime(r.Context(), userID)
-				if err != nil {
-					utility.RespondWithError(w, http.StatusUnauthorized, err.Error())
-					return
-				}
-
-				// checking if refresh token is expired or not
-				// if refresh token is expired then login again
-				// otherwise create new access token and send it with response
-				if time.Now().After(refreshTokenExpiresAt) {
-					utility.RespondWithError(w, http.StatusUnauthorized, "Please login again")
-					return
-				} else {
-					// creating new access token
-					newAccessToken, err := controllers.MakeJWT(struct {
-						UserId string
-						Role   string
-					}{
-						UserId: userID.String(),
-						Role:   parsedSubjects["role"],
-					}, tokenSecret, time.Hour)
-					if err != nil {
-						utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
-						return
-					}
-
-					// calling the authorization middleware to check whether the user is authorized to access this endpoint
-					userAuthorization(w, r, handler, routes, &UserRoleAndId, newAccessToken)
-				}
-			}
-
-			utility.RespondWithError(w, http.StatusUnauthorized, parseError.Error())
-			return
+		if newAccessToken != "" {
+			w.Header().Set(AccessTokenHeader, newAccessToken)
 		}
-
-		// calling the authorization middleware to check whether the user is authorized to access this endpoint
-		userAuthorization(w, r, handler, routes, &UserRoleAndId, "")
+		handler(w, r.WithContext(controllers.WithIDAndRole(r.Context(), UserRoleAndId)))
 	}
 }
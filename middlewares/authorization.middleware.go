@@ -5,43 +5,33 @@ import (
 	"slices"
 
 	"github.com/harshvardha/artOfSoftwareEngineering/controllers"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
 	"github.com/harshvardha/artOfSoftwareEngineering/utility"
 )
 
-func userAuthorization(w http.ResponseWriter, r *http.Request, handler authenticatedRequestHandler, routes map[string][]string, IDAndRole *controllers.IDAndRole, newAccessToken string) {
-	// extracting the endpoint user trying to access
-	endpoint := r.URL.Path
-	var isValidRole bool
+// header carrying the new access token when the one sent with the request had expired
+const AccessTokenHeader = "X-Access-Token"
 
-	// checking if the user is authorized to access the endpoint according to the role
-	switch IDAndRole.Role {
-	case "admin":
-		isValidRole = true
-	case "user":
-		if !slices.Contains(routes[IDAndRole.Role], endpoint) {
-			utility.RespondWithError(w, http.StatusBadRequest, "endpoint not available")
-			return
-		}
-		isValidRole = true
-	default:
-		utility.RespondWithError(w, http.StatusNotFound, "endpoint does not exist")
+func userAuthorization(w http.ResponseWriter, r *http.Request, handler http.HandlerFunc, db *database.Queries, permissions []string, IDAndRole *controllers.IDAndRole, newAccessToken string) {
+	// fetching the permissions granted to the role of the user
+	grantedPermissions, err := db.GetPermissionsByRoleName(r.Context(), IDAndRole.Role)
+	if err != nil {
+		utility.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if isValidRole {
-		if slices.Contains(routes["nil_IDAndRole"], endpoint) {
-			handler(w, r, nil, newAccessToken)
-			isValidRole = false
-			return
-		}
-
-		if slices.Contains(routes["nil_accessToken"], endpoint) {
-			handler(w, r, IDAndRole, "")
-			isValidRole = false
+	// checking if the role has every permission the endpoint requires
+	for _, requiredPermission := range permissions {
+		if !slices.Contains(grantedPermissions, requiredPermission) {
+			utility.RespondWithError(w, http.StatusForbidden, "not allowed to access this endpoint")
 			return
 		}
+	}
 
-		handler(w, r, IDAndRole, newAccessToken)
-		isValidRole = false
+	// delivering the refreshed access token with whatever response the handler writes
+	if newAccessToken != "" {
+		w.Header().Set(AccessTokenHeader, newAccessToken)
 	}
+
+	handler(w, r.WithContext(controllers.WithIDAndRole(r.Context(), IDAndRole)))
 }
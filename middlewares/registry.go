@@ -0,0 +1,52 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/database"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/permission"
+	"github.com/harshvardha/artOfSoftwareEngineering/internal/token"
+)
+
+// Registry mounts every endpoint on the mux, each endpoint is either explicitly public,
+// optionally authenticated or protected by the permissions the role of the user must have
+type Registry struct {
+	mux          *http.ServeMux
+	tokenManager *token.Manager
+	db           *database.Queries
+}
+
+func NewRegistry(mux *http.ServeMux, tokenManager *token.Manager, db *database.Queries) *Registry {
+	return &Registry{
+		mux:          mux,
+		tokenManager: tokenManager,
+		db:           db,
+	}
+}
+
+// Public registers an endpoint anyone can access without an access token
+func (registry *Registry) Public(method string, path string, handler http.HandlerFunc) {
+	registry.mux.HandleFunc(method+" "+path, handler)
+}
+
+// Optional registers a read only endpoint anyone can access, requests carrying
+// an access token are authenticated so the handler can tell who is asking
+func (registry *Registry) Optional(method string, path string, handler http.HandlerFunc) {
+	registry.mux.HandleFunc(method+" "+path, OptionalJWT(handler, registry.tokenManager, registry.db))
+}
+
+// Protected registers an endpoint which requires a valid access token whose role
+// has all the given permissions, it panics if no or unknown permissions are given
+func (registry *Registry) Protected(method string, path string, handler http.HandlerFunc, permissions ...string) {
+	if len(permissions) == 0 {
+		panic(fmt.Sprintf("protected endpoint %s %s registered without permissions", method, path))
+	}
+	for _, requiredPermission := range permissions {
+		if !permission.IsValid(requiredPermission) {
+			panic(fmt.Sprintf("protected endpoint %s %s registered with unknown permission %q", method, path, requiredPermission))
+		}
+	}
+
+	registry.mux.HandleFunc(method+" "+path, ValidateJWT(handler, registry.tokenManager, registry.db, permissions))
+}
@@ -32,13 +32,27 @@ delete from blogs where id = $1;
 select
 blogs.title, blogs.brief, blogs.content_url, blogs.images, blogs.thumbnail_url,
 blogs.code_repo_link, blogs.views,
-blogs.tags, users.username, blogs.created_at
+blogs.tags, users.username, blogs.created_at, blogs.hidden
 from blogs join users on blogs.author = users.id where blogs.id = $1;
 
+-- name: GetBlogAuthorByID :one
+select author from blogs where id = $1;
+
+-- name: GetBlogHideState :one
+select hidden, auto_hidden from blogs where id = $1;
+
+-- name: UpdateBlogHideState :execrows
+update blogs set hidden = sqlc.arg(hidden), auto_hidden = sqlc.arg(auto_hidden), hidden_by = sqlc.narg(hidden_by)
+where id = sqlc.arg(id) and hidden = sqlc.arg(current_hidden) and auto_hidden = sqlc.arg(current_auto_hidden);
+
 -- name: GetAllBlogsByCategory :many
 select 
 id, title, brief, thumbnail_url, views,
-tags, created_at from blogs where category = $1 and created_at < $2 limit $3;
+tags, created_at from blogs where category = sqlc.arg(category) and not hidden
+and (sqlc.narg(before)::timestamp is null or created_at < sqlc.narg(before)::timestamp)
+and (sqlc.narg(after_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by created_at desc, id desc
+limit sqlc.arg(page_limit);
 
 -- name: LikeBlog :exec
 insert into likes(user_id, blog_id, created_at, updated_at)
@@ -57,4 +71,59 @@ select 1 from likes where user_id = $1 and blog_id = $2;
 update blogs set views = views + 1 where id = $1;
 
 -- name: GetViewCount :one
-select views from blogs where id = $1;
\ No newline at end of file
+select views from blogs where id = $1;
+
+-- name: FilterBlogsByNewest :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+where not blogs.hidden
+and (sqlc.narg(category)::text is null or categories.category = sqlc.narg(category)::text)
+and (sqlc.narg(any_tags)::text[] is null or blogs.tags && sqlc.narg(any_tags)::text[])
+and (sqlc.narg(all_tags)::text[] is null or blogs.tags @> sqlc.narg(all_tags)::text[])
+and (sqlc.narg(author)::text is null or users.username = sqlc.narg(author)::text)
+and (sqlc.narg(created_from)::timestamp is null or blogs.created_at >= sqlc.narg(created_from)::timestamp)
+and (sqlc.narg(created_to)::timestamp is null or blogs.created_at < sqlc.narg(created_to)::timestamp)
+and (sqlc.narg(has_code_repo)::boolean is null or (blogs.code_repo_link is not null) = sqlc.narg(has_code_repo)::boolean)
+and (sqlc.narg(after_created_at)::timestamp is null or (blogs.created_at, blogs.id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by blogs.created_at desc, blogs.id desc
+limit sqlc.arg(page_limit);
+
+-- name: FilterBlogsByViews :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+where not blogs.hidden
+and (sqlc.narg(category)::text is null or categories.category = sqlc.narg(category)::text)
+and (sqlc.narg(any_tags)::text[] is null or blogs.tags && sqlc.narg(any_tags)::text[])
+and (sqlc.narg(all_tags)::text[] is null or blogs.tags @> sqlc.narg(all_tags)::text[])
+and (sqlc.narg(author)::text is null or users.username = sqlc.narg(author)::text)
+and (sqlc.narg(created_from)::timestamp is null or blogs.created_at >= sqlc.narg(created_from)::timestamp)
+and (sqlc.narg(created_to)::timestamp is null or blogs.created_at < sqlc.narg(created_to)::timestamp)
+and (sqlc.narg(has_code_repo)::boolean is null or (blogs.code_repo_link is not null) = sqlc.narg(has_code_repo)::boolean)
+and (sqlc.narg(after_views)::int is null or (blogs.views, blogs.id) < (sqlc.narg(after_views)::int, sqlc.narg(after_id)::uuid))
+order by blogs.views desc, blogs.id desc
+limit sqlc.arg(page_limit);
+
+-- name: FilterBlogsByLikes :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+blogs.tags, blogs.created_at, blog_likes.like_count
+from blogs
+join categories on blogs.category = categories.id
+join users on blogs.author = users.id
+cross join lateral (select count(*) as like_count from likes where likes.blog_id = blogs.id) as blog_likes
+where not blogs.hidden
+and (sqlc.narg(category)::text is null or categories.category = sqlc.narg(category)::text)
+and (sqlc.narg(any_tags)::text[] is null or blogs.tags && sqlc.narg(any_tags)::text[])
+and (sqlc.narg(all_tags)::text[] is null or blogs.tags @> sqlc.narg(all_tags)::text[])
+and (sqlc.narg(author)::text is null or users.username = sqlc.narg(author)::text)
+and (sqlc.narg(created_from)::timestamp is null or blogs.created_at >= sqlc.narg(created_from)::timestamp)
+and (sqlc.narg(created_to)::timestamp is null or blogs.created_at < sqlc.narg(created_to)::timestamp)
+and (sqlc.narg(has_code_repo)::boolean is null or (blogs.code_repo_link is not null) = sqlc.narg(has_code_repo)::boolean)
+and (sqlc.narg(after_likes)::bigint is null or (blog_likes.like_count, blogs.id) < (sqlc.narg(after_likes)::bigint, sqlc.narg(after_id)::uuid))
+order by blog_likes.like_count desc, blogs.id desc
+limit sqlc.arg(page_limit);
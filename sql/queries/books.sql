@@ -24,10 +24,17 @@ where id = $6;
 delete from books where id = $1;
 
 -- name: GetBooksByLevel :many
-select id, name, cover_image_url from books where books.level = (select id from book_level where book_level.level = $1);
+select id, name, cover_image_url, created_at from books
+where books.level = (select id from book_level where book_level.level = sqlc.arg(level))
+and (sqlc.narg(after_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by created_at desc, id desc
+limit sqlc.arg(page_limit);
 
 -- name: GetAllBooks :many
-select id, name, cover_image_url from books;
+select id, name, cover_image_url, created_at from books
+where sqlc.narg(after_created_at)::timestamp is null or (created_at, id) < (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid)
+order by created_at desc, id desc
+limit sqlc.arg(page_limit);
 
 -- name: GetAllBooksCount :one
 select count(*) from books;
@@ -16,7 +16,7 @@ returning category, created_at, updated_at;
 delete from categories where id = $1;
 
 -- name: GetAllCategories :many
-select * from categories;
+select * from categories order by category;
 
 -- name: GetCategoryIDByName :one
 select id from categories where category = $1;
\ No newline at end of file
@@ -1,22 +1,147 @@
 -- name: CreateComment :one
 insert into comments(
     id, description, user_id, blog_id,
-    created_at, updated_at
+    parent_id, depth, status, created_at, updated_at
 ) values(
     gen_random_uuid(),
-    $1, $2, $3, NOW(), NOW()
+    $1, $2, $3, $4, $5, $6, NOW(), NOW()
 )
-returning id, description, created_at, updated_at;
+returning id, description, parent_id, depth, status, created_at, updated_at;
+
+-- name: GetCommentThreadInfo :one
+select blog_id, depth, status, deleted_at from comments where id = $1;
+
+-- name: CountApprovedCommentsByUser :one
+select count(*) from comments where user_id = $1 and status = 'approved';
 
 -- name: UpdateCommentByID :one
-update comments set description = $1, updated_at = NOW() where id = $2 and user_id = $3
-returning description, updated_at;
+update comments set description = sqlc.arg(description), status = coalesce(sqlc.narg(status), status), updated_at = NOW()
+where id = sqlc.arg(id) and user_id = sqlc.arg(user_id) and deleted_at is null
+returning description, status, updated_at;
+
+-- name: HasRecentDuplicateComment :one
+select exists(
+    select 1 from comments
+    where user_id = sqlc.arg(user_id) and created_at > sqlc.arg(since)::timestamp and deleted_at is null
+    and lower(regexp_replace(trim(description), '\s+', ' ', 'g')) = lower(regexp_replace(trim(sqlc.arg(description)::text), '\s+', ' ', 'g'))
+    and (sqlc.narg(exclude_id)::uuid is null or id <> sqlc.narg(exclude_id)::uuid)
+);
 
 -- name: GetCommentByBlogID :many
 select
-comments.id, comments.description, users.username,
-users.profile_pic_url, comments.created_at, comments.updated_at
-from comments join users on comments.user_id = users.id where comments.blog_id = $1;
+comments.id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as replies where replies.parent_id = comments.id and replies.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = sqlc.narg(viewer_id)::uuid) as has_user_liked
+from comments join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where comments.blog_id = sqlc.arg(blog_id) and comments.parent_id is null
+and comments.status in ('approved', 'hidden') and not blogs.hidden
+and (sqlc.narg(after_created_at)::timestamp is null or (comments.created_at, comments.id) > (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by comments.created_at, comments.id
+limit sqlc.arg(page_limit);
+
+-- name: GetTopCommentsByBlogID :many
+select
+comments.id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as replies where replies.parent_id = comments.id and replies.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = sqlc.narg(viewer_id)::uuid) as has_user_liked
+from comments join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where comments.blog_id = sqlc.arg(blog_id) and comments.parent_id is null
+and comments.status in ('approved', 'hidden') and not blogs.hidden
+and (sqlc.narg(after_likes)::int is null or (comments.likes, comments.id) < (sqlc.narg(after_likes)::int, sqlc.narg(after_id)::uuid))
+order by comments.likes desc, comments.id desc
+limit sqlc.arg(page_limit);
+
+-- name: GetCommentRepliesByRootIDs :many
+with recursive replies as (
+    select comments.id from comments
+    where comments.parent_id = any(sqlc.arg(root_ids)::uuid[]) and comments.status in ('approved', 'hidden')
+    union all
+    select comments.id from comments join replies on comments.parent_id = replies.id
+    where comments.status in ('approved', 'hidden')
+)
+select
+comments.id, comments.parent_id,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '[removed]' else comments.description end)::text as description,
+comments.depth,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.username end)::text as username,
+(case when comments.deleted_at is not null or comments.status = 'hidden' then '' else users.profile_pic_url end)::text as profile_pic_url,
+comments.created_at, comments.updated_at,
+(select count(*) from comments as children where children.parent_id = comments.id and children.status in ('approved', 'hidden')) as reply_count,
+(comments.deleted_at is not null or comments.status = 'hidden')::boolean as removed,
+comments.likes as likes_count,
+exists(select 1 from comment_likes where comment_likes.comment_id = comments.id and comment_likes.user_id = sqlc.narg(viewer_id)::uuid) as has_user_liked
+from replies
+join comments on replies.id = comments.id
+join users on comments.user_id = users.id
+join blogs on comments.blog_id = blogs.id
+where not blogs.hidden
+order by comments.created_at, comments.id;
+
+-- name: RemoveComment :execrows
+update comments set deleted_at = NOW(), updated_at = NOW()
+where id = $1 and user_id = $2 and deleted_at is null;
+
+-- name: GetModerationQueue :many
+select
+comments.id, comments.blog_id, comments.parent_id, comments.description,
+users.username, comments.created_at, comments.updated_at
+from comments join users on comments.user_id = users.id
+where comments.status = 'pending' and comments.deleted_at is null
+and (sqlc.narg(after_created_at)::timestamp is null or (comments.created_at, comments.id) > (sqlc.narg(after_created_at)::timestamp, sqlc.narg(after_id)::uuid))
+order by comments.created_at, comments.id
+limit sqlc.arg(page_limit);
+
+-- name: SetCommentsStatus :many
+update comments set status = sqlc.arg(status), status_before_hide = null, moderated_by = sqlc.arg(moderated_by), moderated_at = NOW()
+where id = any(sqlc.arg(ids)::uuid[]) and deleted_at is null
+returning id;
+
+-- name: LikeComment :one
+with liked as (
+    insert into comment_likes(user_id, comment_id, created_at, updated_at)
+    select sqlc.arg(user_id)::uuid, comments.id, NOW(), NOW() from comments
+    where comments.id = sqlc.arg(comment_id) and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+    on conflict do nothing
+    returning comment_id
+)
+update comments set likes = likes + (select count(*) from liked)
+where comments.id = sqlc.arg(comment_id) and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+returning likes;
+
+-- name: DislikeComment :one
+with disliked as (
+    delete from comment_likes using comments
+    where comment_likes.comment_id = comments.id and comment_likes.user_id = sqlc.arg(user_id) and comment_likes.comment_id = sqlc.arg(comment_id)
+    and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+    returning comment_likes.comment_id
+)
+update comments set likes = likes - (select count(*) from disliked)
+where comments.id = sqlc.arg(comment_id) and comments.status in ('approved', 'hidden') and comments.deleted_at is null
+returning likes;
+
+-- name: HasUserLikedComment :one
+select exists(select 1 from comment_likes where user_id = $1 and comment_id = $2);
+
+-- name: GetCommentAuthorByID :one
+select user_id from comments where id = $1;
+
+-- name: GetCommentHideState :one
+select status, status_before_hide from comments where id = $1 and deleted_at is null;
 
--- name: RemoveComment :exec
-delete from comments where id = $1 and user_id = $2;
\ No newline at end of file
+-- name: UpdateCommentHideState :execrows
+update comments set status = sqlc.arg(status), status_before_hide = sqlc.narg(status_before_hide)
+where id = sqlc.arg(id) and status = sqlc.arg(current_status) and deleted_at is null;
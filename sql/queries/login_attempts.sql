@@ -0,0 +1,16 @@
+-- name: GetLoginLockout :one
+select locked_until from login_attempts where scope = $1 and identifier = $2;
+
+-- name: RecordLoginFailure :one
+insert into login_attempts(scope, identifier, failed_count, last_failed_at)
+values($1, $2, 1, $3)
+on conflict(scope, identifier) do update set
+    failed_count = case when login_attempts.last_failed_at < sqlc.arg(window_start) then 1 else login_attempts.failed_count + 1 end,
+    last_failed_at = excluded.last_failed_at
+returning failed_count;
+
+-- name: LockLogin :exec
+update login_attempts set locked_until = $1 where scope = $2 and identifier = $3;
+
+-- name: ClearLoginFailures :execrows
+delete from login_attempts where scope = $1 and identifier = $2;
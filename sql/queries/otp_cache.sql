@@ -0,0 +1,37 @@
+-- name: SetOTP :exec
+insert into otp_cache(verification_token, otp, email, purpose, issued_at, attempts, created_at, updated_at)
+values(
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    NOW()
+)
+on conflict(verification_token) do update
+set otp = excluded.otp, email = excluded.email, purpose = excluded.purpose,
+issued_at = excluded.issued_at, attempts = excluded.attempts, updated_at = NOW();
+
+-- name: GetOTP :one
+select otp, email, purpose, issued_at, attempts from otp_cache where verification_token = $1;
+
+-- name: DeleteOTP :execrows
+delete from otp_cache where verification_token = $1;
+
+-- name: DeleteOTPsIssuedBefore :execrows
+delete from otp_cache where issued_at < $1;
+
+-- name: IncrementOTPAttempts :one
+update otp_cache set attempts = attempts + 1, updated_at = NOW() where verification_token = $1
+returning attempts;
+
+-- name: RecordOTPSend :exec
+insert into otp_send_log(email, sent_at) values($1, $2);
+
+-- name: CountOTPSendsSince :one
+select count(*) from otp_send_log where email = $1 and sent_at >= $2;
+
+-- name: DeleteOTPSendsBefore :execrows
+delete from otp_send_log where sent_at < $1;
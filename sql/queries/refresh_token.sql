@@ -1,15 +1,58 @@
+-- name: CreateSession :one
+insert into sessions(
+    id, user_id, device_name, user_agent,
+    last_used_at, expires_at, created_at, updated_at
+) values(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW(),
+    $4,
+    NOW(),
+    NOW()
+)
+returning id;
+
+-- name: GetSessionByID :one
+select sessions.user_id, roles.role_name, sessions.expires_at, sessions.revoked_at
+from sessions join users on sessions.user_id = users.id join roles on users.role_id = roles.id
+where sessions.id = $1;
+
+-- name: TouchSession :exec
+update sessions set last_used_at = NOW(), expires_at = $1, updated_at = NOW() where id = $2;
+
+-- name: RevokeSession :exec
+update sessions set revoked_at = NOW(), updated_at = NOW() where id = $1 and revoked_at is null;
+
+-- name: RevokeAllSessions :exec
+update sessions set revoked_at = NOW(), updated_at = NOW() where user_id = $1 and revoked_at is null;
+
 -- name: CreateRefreshToken :exec
-insert into refresh_token(token, user_id, expires_at, created_at, updated_at)
+insert into refresh_token(token_hash, session_id, user_id, expires_at, created_at, updated_at)
 values(
     $1,
     $2,
     $3,
+    $4,
     NOW(),
     NOW()
 );
 
--- name: GetRefreshTokenExpirationTime :one
-select expires_at from refresh_token where user_id = $1;
+-- name: UseRefreshToken :one
+update refresh_token set used_at = NOW(), updated_at = NOW() where token_hash = $1 and used_at is null
+returning session_id, user_id, expires_at;
+
+-- name: GetRefreshTokenSession :one
+select session_id from refresh_token where token_hash = $1;
 
 -- name: RemoveRefreshToken :exec
-delete from refresh_token where user_id = $1;
\ No newline at end of file
+delete from refresh_token where user_id = $1;
+
+-- name: GetActiveSessionsByUserID :many
+select id, device_name, user_agent, last_used_at, expires_at, created_at from sessions
+where user_id = $1 and revoked_at is null and expires_at > $2
+order by last_used_at desc;
+
+-- name: RevokeUserSession :execrows
+update sessions set revoked_at = NOW(), updated_at = NOW() where id = $1 and user_id = $2 and revoked_at is null;
@@ -0,0 +1,44 @@
+-- name: CreateReport :one
+insert into reports(
+    id, reporter_id, target_type, target_id,
+    reason, details, created_at
+) values(
+    gen_random_uuid(),
+    $1, $2, $3, $4, $5, NOW()
+)
+on conflict (reporter_id, target_type, target_id) do nothing
+returning id, created_at;
+
+-- name: CountOpenReports :one
+select count(*) from reports where target_type = $1 and target_id = $2 and status = 'open';
+
+-- name: GetReportedTargets :many
+select target_type, target_id, count(*) as open_reports,
+array_agg(distinct reason)::text[] as reasons,
+max(created_at)::timestamp as last_reported_at
+from reports
+where status = 'open'
+group by target_type, target_id
+having sqlc.narg(after_open_reports)::bigint is null or (count(*), target_id) < (sqlc.narg(after_open_reports)::bigint, sqlc.narg(after_id)::uuid)
+order by open_reports desc, target_id desc
+limit sqlc.arg(page_limit);
+
+-- name: GetOpenReportsByTarget :many
+select reports.id, users.username as reporter, reports.reason, reports.details, reports.created_at
+from reports join users on reports.reporter_id = users.id
+where reports.target_type = $1 and reports.target_id = $2 and reports.status = 'open'
+order by reports.created_at, reports.id;
+
+-- name: ResolveReports :execrows
+update reports set status = sqlc.arg(status), resolution = sqlc.arg(resolution),
+resolved_by = sqlc.arg(resolved_by), resolved_at = NOW()
+where target_type = sqlc.arg(target_type) and target_id = sqlc.arg(target_id) and status = 'open';
+
+-- name: CreateUserWarning :exec
+insert into user_warnings(
+    id, user_id, issued_by, target_type,
+    target_id, note, created_at
+) values(
+    gen_random_uuid(),
+    $1, $2, $3, $4, $5, NOW()
+);
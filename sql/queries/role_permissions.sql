@@ -0,0 +1,15 @@
+-- name: GetPermissionsByRoleName :many
+select role_permissions.permission from role_permissions
+join roles on role_permissions.role_id = roles.id
+where roles.role_name = $1;
+
+-- name: GetPermissionsByRoleID :many
+select permission, created_at from role_permissions where role_id = $1 order by permission;
+
+-- name: GrantPermission :exec
+insert into role_permissions(role_id, permission, created_at)
+values($1, $2, NOW())
+on conflict(role_id, permission) do nothing;
+
+-- name: RevokePermission :execrows
+delete from role_permissions where role_id = $1 and permission = $2;
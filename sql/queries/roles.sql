@@ -8,18 +8,44 @@ values(
 )
 returning *;
 
--- name: RemoveRole :exec
-delete from roles where id = $1;
+-- name: RemoveRole :execrows
+delete from roles where id = $1 and is_builtin = false;
 
 -- name: GetRoleIdByName :one
 select id from roles where role_name = $1;
 
 -- name: GetRoleById :one
-select role_name from roles where id = $1;
+select role_name, is_builtin from roles where id = $1;
 
 -- name: UpdateRoleById :one
-update roles set role_name = $1, updated_at = NOW() where id = $2
+update roles set role_name = $1, updated_at = NOW() where id = $2 and is_builtin = false
 returning role_name, created_at, updated_at;
 
 -- name: GetAllRoles :many
-select role_name, created_at, updated_at from roles;
\ No newline at end of file
+select id, role_name, is_builtin, created_at, updated_at from roles order by role_name;
+
+-- name: AssignUserRole :execrows
+update users set role_id = $1, updated_at = NOW() where id = $2;
+
+-- name: IsLastAdmin :one
+select (
+    exists(select 1 from users join roles on users.role_id = roles.id where users.id = $1 and roles.role_name = 'admin')
+    and (select count(*) from users join roles on users.role_id = roles.id where roles.role_name = 'admin') = 1
+)::boolean;
+
+-- name: CreateRoleAuditLog :exec
+insert into role_audit_log(id, actor_id, action, role_id, target_user_id, details, created_at)
+values(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+);
+
+-- name: GetRoleAuditLog :many
+select role_audit_log.id, role_audit_log.actor_id, role_audit_log.action, role_audit_log.role_id,
+role_audit_log.target_user_id, role_audit_log.details, role_audit_log.created_at
+from role_audit_log order by created_at desc, id desc limit $1;
\ No newline at end of file
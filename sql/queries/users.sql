@@ -42,18 +42,108 @@ select exists(select 1 from users where email = $1);
 -- name: GetUserByEmailID :one
 select users.id, users.username, users.profile_pic_url, users.password, roles.role_name from users join roles on users.role_id = roles.id where users.email = $1;
 
--- name: SearchBlogsByTitle :many
-select id, title, brief, thumbnail_url, views from blogs
-where title ilike $1 or title ilike $2 or title ilike $3;
+-- name: SearchBlogs :many
+select blogs.id, blogs.title, blogs.brief, blogs.thumbnail_url, blogs.views,
+    ts_headline('english', blogs.title, websearch_to_tsquery('english', sqlc.arg(query)::text), 'HighlightAll=true')::text as title_highlight,
+    ts_headline('english', blogs.brief, websearch_to_tsquery('english', sqlc.arg(query)::text), 'MaxFragments=2, MaxWords=20, MinWords=5')::text as snippet,
+    ranked.rank
+from (
+    select id, (ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg(query)::text)) + similarity(title, sqlc.arg(query)::text))::real as rank
+    from blogs
+    where not hidden
+    and (search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) or title % sqlc.arg(query)::text)
+    and (sqlc.narg(category)::text is null or category = (select id from categories where categories.category = sqlc.narg(category)::text))
+    and (sqlc.narg(tags)::text[] is null or tags @> sqlc.narg(tags)::text[])
+    and (sqlc.narg(year)::int is null or extract(year from created_at)::int = sqlc.narg(year)::int)
+) as ranked join blogs on blogs.id = ranked.id
+where (sqlc.narg(after_rank)::real is null or (ranked.rank, ranked.id) < (sqlc.narg(after_rank)::real, sqlc.narg(after_id)::uuid))
+order by ranked.rank desc, ranked.id desc
+limit sqlc.arg(page_limit);
 
--- name: SearchBlogsByTags :many
-select id, title, brief, thumbnail_url, views from blogs
-where tags && $1;
+-- name: SearchBlogFacets :many
+with matches as (
+    select category, tags, created_at from blogs
+    where not hidden
+    and (search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) or title % sqlc.arg(query)::text)
+    and (sqlc.narg(category)::text is null or category = (select id from categories where categories.category = sqlc.narg(category)::text))
+    and (sqlc.narg(tags)::text[] is null or tags @> sqlc.narg(tags)::text[])
+    and (sqlc.narg(year)::int is null or extract(year from created_at)::int = sqlc.narg(year)::int)
+)
+select 'category'::text as facet, categories.category::text as value, count(*) as count
+from matches join categories on matches.category = categories.id
+group by categories.category
+union all
+select 'tag'::text as facet, tag::text as value, count(*) as count
+from matches cross join unnest(matches.tags) as tag
+group by tag
+union all
+select 'year'::text as facet, extract(year from created_at)::int::text as value, count(*) as count
+from matches
+group by extract(year from created_at)::int
+order by facet, count desc, value;
 
--- name: SearchBooksByTitle :many
-select id, name, cover_image_url from books
-where name ilike $1 and name ilike $2 and name ilike $3;
+-- name: SearchBooks :many
+select books.id, books.name, books.cover_image_url,
+    ts_headline('english', books.name, websearch_to_tsquery('english', sqlc.arg(query)::text), 'HighlightAll=true')::text as name_highlight,
+    ts_headline('english', books.review, websearch_to_tsquery('english', sqlc.arg(query)::text), 'MaxFragments=2, MaxWords=20, MinWords=5')::text as snippet,
+    ranked.rank
+from (
+    select id, (ts_rank(search_vector, websearch_to_tsquery('english', sqlc.arg(query)::text)) + similarity(name, sqlc.arg(query)::text))::real as rank
+    from books
+    where (search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) or name % sqlc.arg(query)::text)
+    and (sqlc.narg(level)::text is null or level = (select id from book_level where book_level.level = sqlc.narg(level)::text))
+    and (sqlc.narg(tags)::text[] is null or tags @> sqlc.narg(tags)::text[])
+    and (sqlc.narg(year)::int is null or extract(year from created_at)::int = sqlc.narg(year)::int)
+) as ranked join books on books.id = ranked.id
+where (sqlc.narg(after_rank)::real is null or (ranked.rank, ranked.id) < (sqlc.narg(after_rank)::real, sqlc.narg(after_id)::uuid))
+order by ranked.rank desc, ranked.id desc
+limit sqlc.arg(page_limit);
 
--- name: SearchBooksByTags :many
-select id, name, cover_image_url from books
-where tags && $1;
\ No newline at end of file
+-- name: SearchBookFacets :many
+with matches as (
+    select level, tags, created_at from books
+    where (search_vector @@ websearch_to_tsquery('english', sqlc.arg(query)::text) or name % sqlc.arg(query)::text)
+    and (sqlc.narg(level)::text is null or level = (select id from book_level where book_level.level = sqlc.narg(level)::text))
+    and (sqlc.narg(tags)::text[] is null or tags @> sqlc.narg(tags)::text[])
+    and (sqlc.narg(year)::int is null or extract(year from created_at)::int = sqlc.narg(year)::int)
+)
+select 'level'::text as facet, book_level.level::text as value, count(*) as count
+from matches join book_level on matches.level = book_level.id
+group by book_level.level
+union all
+select 'tag'::text as facet, tag::text as value, count(*) as count
+from matches cross join unnest(matches.tags) as tag
+group by tag
+union all
+select 'year'::text as facet, extract(year from created_at)::int::text as value, count(*) as count
+from matches
+group by extract(year from created_at)::int
+order by facet, count desc, value;
+
+-- name: SuggestBlogTitles :many
+select title from blogs
+where not hidden and (title ilike escape_like(sqlc.arg(query)::text) || '%' escape '\' or sqlc.arg(query)::text <% title)
+order by word_similarity(sqlc.arg(query)::text, title) desc, title
+limit sqlc.arg(page_limit);
+
+-- name: SuggestBookNames :many
+select name from books
+where name ilike escape_like(sqlc.arg(query)::text) || '%' escape '\' or sqlc.arg(query)::text <% name
+order by word_similarity(sqlc.arg(query)::text, name) desc, name
+limit sqlc.arg(page_limit);
+
+-- name: SuggestTags :many
+select tag::text from (
+    select unnest(tags) as tag from blogs where not hidden
+    union
+    select unnest(tags) as tag from books
+) as all_tags
+where tag ilike escape_like(sqlc.arg(query)::text) || '%' escape '\' or sqlc.arg(query)::text <% tag
+order by word_similarity(sqlc.arg(query)::text, tag) desc, tag
+limit sqlc.arg(page_limit);
+
+-- name: SuggestCategories :many
+select category from categories
+where category ilike escape_like(sqlc.arg(query)::text) || '%' escape '\' or sqlc.arg(query)::text <% category
+order by word_similarity(sqlc.arg(query)::text, category) desc, category
+limit sqlc.arg(page_limit);
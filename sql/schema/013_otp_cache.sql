@@ -0,0 +1,11 @@
+-- +goose Up
+create table otp_cache(
+    verification_token text primary key,
+    otp text not null,
+    issued_at timestamp not null,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+
+-- +goose Down
+drop table otp_cache;
@@ -0,0 +1,11 @@
+-- +goose Up
+alter table otp_cache add column attempts int not null default 0;
+create table otp_send_log(
+    email text not null,
+    sent_at timestamp not null
+);
+create index idx_otp_send_log_email_sent_at on otp_send_log(email, sent_at);
+
+-- +goose Down
+drop table otp_send_log;
+alter table otp_cache drop column attempts;
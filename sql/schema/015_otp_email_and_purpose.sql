@@ -0,0 +1,7 @@
+-- +goose Up
+alter table otp_cache add column email text not null default '';
+alter table otp_cache add column purpose text not null default '';
+
+-- +goose Down
+alter table otp_cache drop column purpose;
+alter table otp_cache drop column email;
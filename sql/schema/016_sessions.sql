@@ -0,0 +1,36 @@
+-- +goose Up
+create table sessions(
+    id uuid not null primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    device_name text not null,
+    user_agent text not null,
+    last_used_at timestamp not null,
+    expires_at timestamp not null,
+    revoked_at timestamp,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+create index idx_sessions_user_id on sessions(user_id);
+
+drop table refresh_token;
+create table refresh_token(
+    token_hash text primary key,
+    session_id uuid not null references sessions(id) on delete cascade,
+    user_id uuid not null references users(id) on delete cascade,
+    expires_at timestamp not null,
+    used_at timestamp,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
+create index idx_refresh_token_session_id on refresh_token(session_id);
+
+-- +goose Down
+drop table refresh_token;
+drop table sessions;
+create table refresh_token(
+    token text primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    expires_at timestamp not null,
+    created_at timestamp not null,
+    updated_at timestamp not null
+);
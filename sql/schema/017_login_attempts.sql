@@ -0,0 +1,12 @@
+-- +goose Up
+create table login_attempts(
+    scope text not null,
+    identifier text not null,
+    failed_count int not null default 0,
+    last_failed_at timestamp not null,
+    locked_until timestamp,
+    primary key(scope, identifier)
+);
+
+-- +goose Down
+drop table login_attempts;
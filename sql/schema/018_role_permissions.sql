@@ -0,0 +1,34 @@
+-- +goose Up
+create table role_permissions(
+    role_id uuid not null references roles(id) on delete cascade,
+    permission text not null,
+    created_at timestamp not null,
+    primary key(role_id, permission)
+);
+
+-- admin keeps access to every endpoint
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, permissions.permission, NOW() from roles, unnest(array[
+    'account:manage', 'session:manage',
+    'user:read', 'user:unlock',
+    'book:create', 'book:read', 'book:update', 'book:delete',
+    'category:create', 'category:read', 'category:update', 'category:delete',
+    'blog:create', 'blog:read', 'blog:update', 'blog:delete', 'blog:like', 'blog:view',
+    'comment:create', 'comment:read', 'comment:update', 'comment:delete',
+    'permission:manage'
+]) as permissions(permission)
+where roles.role_name = 'admin';
+
+-- user manages its own account and sessions, reads users, books and blogs, likes and views blogs and creates comments
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, permissions.permission, NOW() from roles, unnest(array[
+    'account:manage', 'session:manage',
+    'user:read',
+    'book:read',
+    'blog:read', 'blog:like', 'blog:view',
+    'comment:create'
+]) as permissions(permission)
+where roles.role_name = 'user';
+
+-- +goose Down
+drop table role_permissions;
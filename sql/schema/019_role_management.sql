@@ -0,0 +1,28 @@
+-- +goose Up
+alter table roles add column is_builtin boolean not null default false;
+update roles set is_builtin = true where role_name in ('admin', 'user');
+
+-- deleting a role must not silently delete every user having it
+alter table users drop constraint users_role_id_fkey;
+alter table users add constraint users_role_id_fkey foreign key (role_id) references roles(id) on delete restrict;
+
+create table role_audit_log(
+    id uuid not null primary key,
+    actor_id uuid references users(id) on delete set null,
+    action text not null,
+    role_id uuid not null,
+    target_user_id uuid references users(id) on delete set null,
+    details text not null,
+    created_at timestamp not null
+);
+create index idx_role_audit_log_created_at on role_audit_log(created_at);
+
+insert into role_permissions(role_id, permission, created_at)
+select id, 'role:manage', NOW() from roles where role_name = 'admin';
+
+-- +goose Down
+delete from role_permissions where permission = 'role:manage';
+drop table role_audit_log;
+alter table users drop constraint users_role_id_fkey;
+alter table users add constraint users_role_id_fkey foreign key (role_id) references roles(id) on delete cascade;
+alter table roles drop column is_builtin;
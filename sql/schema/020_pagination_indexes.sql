@@ -0,0 +1,11 @@
+-- +goose Up
+create index idx_blogs_category_created_at on blogs(category, created_at desc, id desc);
+create index idx_books_created_at on books(created_at desc, id desc);
+create index idx_books_level_created_at on books(level, created_at desc, id desc);
+create index idx_comments_blog_id_created_at on comments(blog_id, created_at, id);
+
+-- +goose Down
+drop index if exists idx_blogs_category_created_at;
+drop index if exists idx_books_created_at;
+drop index if exists idx_books_level_created_at;
+drop index if exists idx_comments_blog_id_created_at;
@@ -0,0 +1,9 @@
+-- +goose Up
+create index idx_blogs_created_at on blogs(created_at desc, id desc);
+create index idx_blogs_views on blogs(views desc, id desc);
+create index idx_likes_blog_id on likes(blog_id);
+
+-- +goose Down
+drop index if exists idx_blogs_created_at;
+drop index if exists idx_blogs_views;
+drop index if exists idx_likes_blog_id;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- array_to_string is only stable, generated columns need an immutable expression
+-- +goose StatementBegin
+create function immutable_array_to_string(text[]) returns text
+language sql immutable parallel safe
+as $$ select array_to_string($1, ' ') $$;
+-- +goose StatementEnd
+
+alter table blogs add column search_vector tsvector not null generated always as (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('english', immutable_array_to_string(tags)), 'A') ||
+    setweight(to_tsvector('english', brief), 'B')
+) stored;
+alter table books add column search_vector tsvector not null generated always as (
+    setweight(to_tsvector('english', name), 'A') ||
+    setweight(to_tsvector('english', immutable_array_to_string(tags)), 'A') ||
+    setweight(to_tsvector('english', review), 'B')
+) stored;
+create index idx_blogs_search_vector on blogs using gin(search_vector);
+create index idx_books_search_vector on books using gin(search_vector);
+
+-- +goose Down
+drop index if exists idx_blogs_search_vector;
+drop index if exists idx_books_search_vector;
+alter table blogs drop column search_vector;
+alter table books drop column search_vector;
+drop function if exists immutable_array_to_string(text[]);
@@ -0,0 +1,9 @@
+-- +goose Up
+alter table comments add column parent_id uuid references comments(id) on delete cascade;
+alter table comments add column depth int not null default 0;
+create index idx_comments_parent_id_created_at on comments(parent_id, created_at, id);
+
+-- +goose Down
+drop index if exists idx_comments_parent_id_created_at;
+alter table comments drop column depth;
+alter table comments drop column parent_id;
@@ -0,0 +1,19 @@
+-- +goose Up
+create table comment_likes(
+    user_id uuid not null references users(id) on delete cascade,
+    comment_id uuid not null references comments(id) on delete cascade,
+    created_at timestamp not null,
+    updated_at timestamp not null,
+    unique(user_id, comment_id)
+);
+
+-- comments.likes is kept in sync with comment_likes by the like and dislike queries
+create index idx_comments_blog_id_likes on comments(blog_id, likes desc, id desc);
+
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, 'comment:like', NOW() from roles where roles.role_name in ('admin', 'user');
+
+-- +goose Down
+delete from role_permissions where permission = 'comment:like';
+drop index if exists idx_comments_blog_id_likes;
+drop table comment_likes;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- existing comments were published without moderation so they start approved
+alter table comments add column status text not null default 'approved'
+    check (status in ('pending', 'approved', 'rejected', 'hidden'));
+alter table comments alter column status set default 'pending';
+alter table comments add column moderated_by uuid references users(id) on delete set null;
+alter table comments add column moderated_at timestamp;
+
+-- removed comments stay in their threads and are rendered as removed
+alter table comments add column deleted_at timestamp;
+
+create index idx_comments_status_created_at on comments(status, created_at, id);
+
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, 'comment:moderate', NOW() from roles where roles.role_name = 'admin';
+
+-- +goose Down
+delete from role_permissions where permission = 'comment:moderate';
+drop index if exists idx_comments_status_created_at;
+alter table comments drop column deleted_at;
+alter table comments drop column moderated_at;
+alter table comments drop column moderated_by;
+alter table comments drop column status;
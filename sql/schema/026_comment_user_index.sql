@@ -0,0 +1,5 @@
+-- +goose Up
+create index idx_comments_user_id_created_at on comments(user_id, created_at);
+
+-- +goose Down
+drop index if exists idx_comments_user_id_created_at;
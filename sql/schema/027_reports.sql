@@ -0,0 +1,41 @@
+-- +goose Up
+create table reports(
+    id uuid not null primary key,
+    reporter_id uuid not null references users(id) on delete cascade,
+    target_type text not null check (target_type in ('blog', 'comment')),
+    target_id uuid not null,
+    reason text not null check (reason in ('spam', 'harassment', 'hate_speech', 'misinformation', 'off_topic', 'other')),
+    details text not null default '',
+    status text not null default 'open' check (status in ('open', 'dismissed', 'actioned')),
+    resolution text check (resolution in ('dismiss', 'hide', 'warn')),
+    resolved_by uuid references users(id) on delete set null,
+    resolved_at timestamp,
+    created_at timestamp not null,
+    unique(reporter_id, target_type, target_id)
+);
+create index idx_reports_open_target on reports(target_type, target_id) where status = 'open';
+
+create table user_warnings(
+    id uuid not null primary key,
+    user_id uuid not null references users(id) on delete cascade,
+    issued_by uuid references users(id) on delete set null,
+    target_type text not null,
+    target_id uuid not null,
+    note text not null,
+    created_at timestamp not null
+);
+create index idx_user_warnings_user_id on user_warnings(user_id, created_at);
+
+-- hidden blogs are left out of every public listing until the reports against them are dismissed
+alter table blogs add column hidden boolean not null default false;
+
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, 'report:create', NOW() from roles where roles.role_name in ('admin', 'user');
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, 'report:manage', NOW() from roles where roles.role_name = 'admin';
+
+-- +goose Down
+delete from role_permissions where permission in ('report:create', 'report:manage');
+alter table blogs drop column hidden;
+drop table user_warnings;
+drop table reports;
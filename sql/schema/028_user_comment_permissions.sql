@@ -0,0 +1,27 @@
+-- +goose Up
+-- users edit and remove their own comments, ownership is checked by the queries
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, permissions.permission, NOW() from roles, unnest(array[
+    'comment:update', 'comment:delete'
+]) as permissions(permission)
+where roles.role_name = 'user'
+on conflict do nothing;
+
+-- reading books, blogs, categories and comments is public so these permissions are never checked
+delete from role_permissions where permission in ('book:read', 'blog:read', 'category:read', 'comment:read');
+
+-- +goose Down
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, permissions.permission, NOW() from roles, unnest(array[
+    'book:read', 'category:read', 'blog:read', 'comment:read'
+]) as permissions(permission)
+where roles.role_name = 'admin';
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, permissions.permission, NOW() from roles, unnest(array[
+    'book:read', 'blog:read'
+]) as permissions(permission)
+where roles.role_name = 'user';
+
+delete from role_permissions
+where permission in ('comment:update', 'comment:delete')
+and role_id in (select id from roles where role_name = 'user');
@@ -0,0 +1,6 @@
+-- +goose Up
+insert into role_permissions(role_id, permission, created_at)
+select roles.id, 'metrics:read', NOW() from roles where roles.role_name = 'admin';
+
+-- +goose Down
+delete from role_permissions where permission = 'metrics:read';
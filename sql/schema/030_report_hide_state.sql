@@ -0,0 +1,14 @@
+-- +goose Up
+-- status a comment had before reports hid it, dismissing the reports restores it
+alter table comments add column status_before_hide text
+    check (status_before_hide in ('pending', 'approved', 'rejected', 'hidden'));
+
+-- reports only hide blogs which are visible and dismissing them only shows blogs hidden
+-- by reports, blogs hidden by a moderator stay hidden
+alter table blogs add column auto_hidden boolean not null default false;
+alter table blogs add column hidden_by uuid references users(id) on delete set null;
+
+-- +goose Down
+alter table blogs drop column hidden_by;
+alter table blogs drop column auto_hidden;
+alter table comments drop column status_before_hide;
@@ -0,0 +1,10 @@
+-- +goose Up
+-- escapes the wildcards of a value matched as a literal prefix with like and ilike
+-- +goose StatementBegin
+create function escape_like(text) returns text
+language sql immutable parallel safe
+as $$ select replace(replace(replace($1, '\', '\\'), '%', '\%'), '_', '\_') $$;
+-- +goose StatementEnd
+
+-- +goose Down
+drop function escape_like(text);